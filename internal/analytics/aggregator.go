@@ -0,0 +1,205 @@
+package analytics
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHourlyRetention y DefaultDailyRetention son las ventanas que se
+	// conservan de cada serie temporal.
+	DefaultHourlyRetention = 48 * time.Hour
+	DefaultDailyRetention  = 90 * 24 * time.Hour
+
+	// maxReferrers acota los referrers distintos por código; el resto se
+	// cuenta como otherReferrer.
+	maxReferrers   = 50
+	otherReferrer  = "other"
+	directReferrer = "direct"
+
+	// forgetGrace es cuánto se recuerda un código olvidado para descartar los
+	// clics que seguían en la cola del pipeline al olvidarlo.
+	forgetGrace = time.Minute
+)
+
+// Bucket es el número de clics en un intervalo que empieza en Start.
+type Bucket struct {
+	Start  time.Time `json:"start"`
+	Clicks uint64    `json:"clicks"`
+}
+
+// Stats son las estadísticas agregadas de un código.
+type Stats struct {
+	Code        string    `json:"code"`
+	TotalClicks uint64    `json:"total_clicks"`
+	LastClick   time.Time `json:"last_click,omitzero"`
+	// Hourly y Daily solo incluyen los intervalos con clics, en orden cronológico (UTC).
+	Hourly []Bucket `json:"hourly"`
+	Daily  []Bucket `json:"daily"`
+	// Referrers cuenta los clics por host de origen ("direct" sin referrer).
+	Referrers map[string]uint64 `json:"referrers,omitempty"`
+}
+
+type linkCounters struct {
+	total     uint64
+	lastClick time.Time
+	hourly    map[int64]uint64
+	daily     map[int64]uint64
+	referrers map[string]uint64
+}
+
+// Aggregator es un Sink que mantiene contadores por código en memoria.
+type Aggregator struct {
+	hourlyRetention time.Duration
+	dailyRetention  time.Duration
+
+	mu    sync.RWMutex
+	links map[string]*linkCounters
+	// forgotten guarda cuándo se olvidó cada código; sus clics anteriores se
+	// descartan.
+	forgotten map[string]time.Time
+}
+
+// NewAggregator crea un agregador con las retenciones por defecto.
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		hourlyRetention: DefaultHourlyRetention,
+		dailyRetention:  DefaultDailyRetention,
+		links:           make(map[string]*linkCounters),
+		forgotten:       make(map[string]time.Time),
+	}
+}
+
+// Consume suma click a los contadores de su código. Los clics anteriores a
+// un Forget del código se descartan.
+func (a *Aggregator) Consume(click Click) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if forgottenAt, ok := a.forgotten[click.Code]; ok && !click.Time.After(forgottenAt) {
+		return
+	}
+
+	c, ok := a.links[click.Code]
+	if !ok {
+		c = &linkCounters{
+			hourly:    make(map[int64]uint64),
+			daily:     make(map[int64]uint64),
+			referrers: make(map[string]uint64),
+		}
+		a.links[click.Code] = c
+	}
+
+	c.total++
+	if click.Time.After(c.lastClick) {
+		c.lastClick = click.Time
+	}
+
+	addToBucket(c.hourly, click.Time.UTC().Truncate(time.Hour), a.hourlyRetention)
+	addToBucket(c.daily, startOfDay(click.Time), a.dailyRetention)
+
+	referrer := referrerHost(click.Referrer)
+	if _, seen := c.referrers[referrer]; !seen && len(c.referrers) >= maxReferrers {
+		referrer = otherReferrer
+	}
+	c.referrers[referrer]++
+}
+
+// Stats devuelve las estadísticas de code. Un código sin clics devuelve
+// estadísticas vacías.
+func (a *Aggregator) Stats(code string) Stats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	stats := Stats{Code: code, Hourly: []Bucket{}, Daily: []Bucket{}}
+	c, ok := a.links[code]
+	if !ok {
+		return stats
+	}
+
+	stats.TotalClicks = c.total
+	stats.LastClick = c.lastClick
+	stats.Hourly = sortedBuckets(c.hourly)
+	stats.Daily = sortedBuckets(c.daily)
+	stats.Referrers = make(map[string]uint64, len(c.referrers))
+	for referrer, clicks := range c.referrers {
+		stats.Referrers[referrer] = clicks
+	}
+	return stats
+}
+
+// TotalClicks devuelve el número total de clics de code.
+func (a *Aggregator) TotalClicks(code string) uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if c, ok := a.links[code]; ok {
+		return c.total
+	}
+	return 0
+}
+
+// Forget elimina las estadísticas de codes, p. ej. al borrar, purgar o
+// expulsar el enlace para que un alias reutilizado no herede los clics
+// anteriores. Los clics de codes que aún estén en cola tampoco se cuentan.
+func (a *Aggregator) Forget(codes ...string) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for code, forgottenAt := range a.forgotten {
+		if now.Sub(forgottenAt) > forgetGrace {
+			delete(a.forgotten, code)
+		}
+	}
+	for _, code := range codes {
+		delete(a.links, code)
+		a.forgotten[code] = now
+	}
+}
+
+// addToBucket suma un clic al intervalo start y, al abrir uno nuevo, descarta
+// los que quedaron fuera de la retención.
+func addToBucket(buckets map[int64]uint64, start time.Time, retention time.Duration) {
+	key := start.Unix()
+	if _, ok := buckets[key]; !ok {
+		cutoff := start.Add(-retention).Unix()
+		for k := range buckets {
+			if k <= cutoff {
+				delete(buckets, k)
+			}
+		}
+	}
+	buckets[key]++
+}
+
+func sortedBuckets(buckets map[int64]uint64) []Bucket {
+	result := make([]Bucket, 0, len(buckets))
+	for start, clicks := range buckets {
+		result = append(result, Bucket{Start: time.Unix(start, 0).UTC(), Clicks: clicks})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// referrerHost reduce el referrer a su host para agrupar y no guardar rutas.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return otherReferrer
+	}
+	return strings.ToLower(u.Hostname())
+}
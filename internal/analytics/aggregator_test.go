@@ -0,0 +1,129 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAggregator_Stats(t *testing.T) {
+	aggregator := NewAggregator()
+	base := time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)
+
+	aggregator.Consume(Click{Code: "abc", Time: base, Referrer: "https://news.example.com/post/1"})
+	aggregator.Consume(Click{Code: "abc", Time: base.Add(10 * time.Minute), Referrer: "https://News.Example.com/other"})
+	aggregator.Consume(Click{Code: "abc", Time: base.Add(time.Hour)})
+	aggregator.Consume(Click{Code: "abc", Time: base.Add(24 * time.Hour)})
+	aggregator.Consume(Click{Code: "other", Time: base})
+
+	stats := aggregator.Stats("abc")
+	if stats.TotalClicks != 4 {
+		t.Errorf("Expected 4 clicks, got %d", stats.TotalClicks)
+	}
+	if !stats.LastClick.Equal(base.Add(24 * time.Hour)) {
+		t.Errorf("Unexpected last click %v", stats.LastClick)
+	}
+
+	expectedHourly := []Bucket{
+		{Start: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Clicks: 2},
+		{Start: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), Clicks: 1},
+		{Start: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), Clicks: 1},
+	}
+	if fmt.Sprint(stats.Hourly) != fmt.Sprint(expectedHourly) {
+		t.Errorf("Expected hourly %v, got %v", expectedHourly, stats.Hourly)
+	}
+
+	expectedDaily := []Bucket{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Clicks: 3},
+		{Start: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Clicks: 1},
+	}
+	if fmt.Sprint(stats.Daily) != fmt.Sprint(expectedDaily) {
+		t.Errorf("Expected daily %v, got %v", expectedDaily, stats.Daily)
+	}
+
+	if stats.Referrers["news.example.com"] != 2 || stats.Referrers["direct"] != 2 {
+		t.Errorf("Unexpected referrers %v", stats.Referrers)
+	}
+}
+
+func TestAggregator_StatsUnknownCode(t *testing.T) {
+	stats := NewAggregator().Stats("missing")
+	if stats.TotalClicks != 0 || stats.Hourly == nil || stats.Daily == nil {
+		t.Errorf("Expected empty stats with non-nil buckets, got %+v", stats)
+	}
+}
+
+func TestAggregator_Retention(t *testing.T) {
+	aggregator := NewAggregator()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	aggregator.Consume(Click{Code: "abc", Time: base})
+	aggregator.Consume(Click{Code: "abc", Time: base.Add(DefaultHourlyRetention + time.Hour)})
+
+	stats := aggregator.Stats("abc")
+	if len(stats.Hourly) != 1 {
+		t.Errorf("Expected old hourly bucket to be pruned, got %v", stats.Hourly)
+	}
+	if len(stats.Daily) != 2 || stats.TotalClicks != 2 {
+		t.Errorf("Expected daily buckets and total to be kept, got %+v", stats)
+	}
+}
+
+func TestAggregator_ReferrerCap(t *testing.T) {
+	aggregator := NewAggregator()
+
+	for i := 0; i < maxReferrers+10; i++ {
+		aggregator.Consume(Click{Code: "abc", Time: time.Now(), Referrer: fmt.Sprintf("https://site%d.com", i)})
+	}
+
+	stats := aggregator.Stats("abc")
+	if len(stats.Referrers) != maxReferrers+1 {
+		t.Errorf("Expected %d referrer entries, got %d", maxReferrers+1, len(stats.Referrers))
+	}
+	if stats.Referrers["other"] != 10 {
+		t.Errorf("Expected 10 clicks grouped as other, got %d", stats.Referrers["other"])
+	}
+}
+
+func TestAggregator_TotalClicksAndForget(t *testing.T) {
+	aggregator := NewAggregator()
+
+	aggregator.Consume(Click{Code: "abc", Time: time.Now()})
+	aggregator.Consume(Click{Code: "abc", Time: time.Now()})
+	if clicks := aggregator.TotalClicks("abc"); clicks != 2 {
+		t.Errorf("Expected 2 clicks, got %d", clicks)
+	}
+
+	aggregator.Forget("abc")
+	if clicks := aggregator.TotalClicks("abc"); clicks != 0 {
+		t.Errorf("Expected 0 clicks after Forget, got %d", clicks)
+	}
+
+	aggregator.Consume(Click{Code: "a", Time: time.Now()})
+	aggregator.Consume(Click{Code: "b", Time: time.Now()})
+	aggregator.Consume(Click{Code: "c", Time: time.Now()})
+	aggregator.Forget("a", "b")
+	if a, b, c := aggregator.TotalClicks("a"), aggregator.TotalClicks("b"), aggregator.TotalClicks("c"); a != 0 || b != 0 || c != 1 {
+		t.Errorf("Expected only c to keep its clicks, got %d %d %d", a, b, c)
+	}
+}
+
+func TestAggregator_ForgetDropsPendingClicks(t *testing.T) {
+	aggregator := NewAggregator()
+	pipeline := NewPipeline(Options{BufferSize: 10}, aggregator)
+
+	// El clic queda en la cola hasta que arranca el pipeline
+	pipeline.Record(Click{Code: "abc", Time: time.Now()})
+	aggregator.Forget("abc")
+	pipeline.Start()
+	pipeline.Stop()
+	if clicks := aggregator.TotalClicks("abc"); clicks != 0 {
+		t.Errorf("Expected the pending click to be dropped, got %d", clicks)
+	}
+
+	// Los clics posteriores, p. ej. de un alias reutilizado, sí cuentan
+	aggregator.Consume(Click{Code: "abc", Time: time.Now().Add(time.Millisecond)})
+	if clicks := aggregator.TotalClicks("abc"); clicks != 1 {
+		t.Errorf("Expected a later click to count, got %d", clicks)
+	}
+}
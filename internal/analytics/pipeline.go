@@ -0,0 +1,145 @@
+// Package analytics registra los clics en enlaces cortos sin bloquear las
+// redirecciones y mantiene estadísticas agregadas por código.
+package analytics
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Click es un evento de redirección.
+type Click struct {
+	Code      string
+	Time      time.Time
+	Referrer  string
+	UserAgent string
+	// IPHash es la IP del cliente pasada por HMAC con la sal del pipeline.
+	// La IP en claro nunca se guarda.
+	IPHash string
+}
+
+// Sink consume los clics que entrega el pipeline. Consume se llama siempre
+// desde la misma goroutine.
+type Sink interface {
+	Consume(click Click)
+}
+
+// Options configura un Pipeline.
+type Options struct {
+	// BufferSize es la capacidad de la cola. Con la cola llena los clics se descartan.
+	BufferSize int
+	// Salt es la clave del HMAC de las IPs. Vacía usa una aleatoria, de modo
+	// que los hashes no son comparables entre reinicios.
+	Salt []byte
+}
+
+// Pipeline entrega los clics a los sinks desde una goroutine propia a través
+// de una cola acotada.
+type Pipeline struct {
+	events  chan Click
+	sinks   []Sink
+	salt    []byte
+	dropped atomic.Uint64
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPipeline crea un pipeline que entrega los clics a sinks.
+func NewPipeline(opts Options, sinks ...Sink) *Pipeline {
+	if opts.BufferSize < 1 {
+		opts.BufferSize = 1
+	}
+	salt := opts.Salt
+	if len(salt) == 0 {
+		salt = make([]byte, 32)
+		rand.Read(salt)
+	}
+	return &Pipeline{
+		events: make(chan Click, opts.BufferSize),
+		sinks:  sinks,
+		salt:   salt,
+	}
+}
+
+// HashIP devuelve el identificador anónimo de una IP de cliente.
+func (p *Pipeline) HashIP(ip string) string {
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Record encola click sin bloquear. Devuelve false si la cola está llena y
+// el clic se descartó.
+func (p *Pipeline) Record(click Click) bool {
+	select {
+	case p.events <- click:
+		return true
+	default:
+		p.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped devuelve el número de clics descartados por tener la cola llena.
+func (p *Pipeline) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Start lanza la goroutine que entrega los clics. Llamarlo con el pipeline
+// ya activo no hace nada.
+func (p *Pipeline) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run(p.stop, p.done)
+}
+
+// Stop entrega los clics pendientes y detiene la goroutine.
+func (p *Pipeline) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.stop, p.done = nil, nil
+}
+
+func (p *Pipeline) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		select {
+		case click := <-p.events:
+			p.deliver(click)
+		case <-stop:
+			// Vaciar la cola antes de salir
+			for {
+				select {
+				case click := <-p.events:
+					p.deliver(click)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *Pipeline) deliver(click Click) {
+	for _, sink := range p.sinks {
+		sink.Consume(click)
+	}
+}
@@ -0,0 +1,114 @@
+package analytics
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink guarda los clics recibidos.
+type recordingSink struct {
+	mu     sync.Mutex
+	clicks []Click
+}
+
+func (s *recordingSink) Consume(click Click) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clicks = append(s.clicks, click)
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clicks)
+}
+
+// blockingSink bloquea la entrega hasta que se cierra release.
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) Consume(click Click) {
+	<-s.release
+}
+
+func TestPipeline_DeliversToAllSinks(t *testing.T) {
+	first, second := &recordingSink{}, &recordingSink{}
+	pipeline := NewPipeline(Options{BufferSize: 16}, first, second)
+	pipeline.Start()
+
+	for i := 0; i < 10; i++ {
+		if !pipeline.Record(Click{Code: "abc", Time: time.Now()}) {
+			t.Fatal("Expected click to be queued")
+		}
+	}
+	pipeline.Stop()
+
+	if first.count() != 10 || second.count() != 10 {
+		t.Errorf("Expected 10 clicks in each sink, got %d and %d", first.count(), second.count())
+	}
+}
+
+func TestPipeline_RecordNeverBlocks(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	pipeline := NewPipeline(Options{BufferSize: 2}, sink)
+	pipeline.Start()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			pipeline.Record(Click{Code: "abc"})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Record not to block with a full queue")
+	}
+
+	// Uno en el sink, dos en la cola y el resto descartados
+	if dropped := pipeline.Dropped(); dropped < 97 {
+		t.Errorf("Expected at least 97 dropped clicks, got %d", dropped)
+	}
+
+	close(sink.release)
+	pipeline.Stop()
+}
+
+func TestPipeline_StopDrainsQueue(t *testing.T) {
+	sink := &recordingSink{}
+	pipeline := NewPipeline(Options{BufferSize: 100}, sink)
+
+	// Los clics encolados antes de Start se entregan al parar
+	for i := 0; i < 50; i++ {
+		pipeline.Record(Click{Code: "abc"})
+	}
+	pipeline.Start()
+	pipeline.Stop()
+	pipeline.Stop()
+
+	if sink.count() != 50 {
+		t.Errorf("Expected 50 clicks delivered, got %d", sink.count())
+	}
+}
+
+func TestPipeline_HashIP(t *testing.T) {
+	pipeline := NewPipeline(Options{Salt: []byte("secret")})
+
+	hash := pipeline.HashIP("203.0.113.7")
+	if hash == "203.0.113.7" || len(hash) != 32 {
+		t.Errorf("Expected 32-char anonymized hash, got %q", hash)
+	}
+	if pipeline.HashIP("203.0.113.7") != hash {
+		t.Error("Expected hash to be stable for the same salt")
+	}
+	if pipeline.HashIP("203.0.113.8") == hash {
+		t.Error("Expected different IPs to hash differently")
+	}
+	if NewPipeline(Options{Salt: []byte("other")}).HashIP("203.0.113.7") == hash {
+		t.Error("Expected hash to depend on the salt")
+	}
+}
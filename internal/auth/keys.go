@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scope es un permiso que puede tener una clave de API.
+type Scope string
+
+const (
+	// ScopeCreate permite acortar URLs.
+	ScopeCreate Scope = "create"
+	// ScopeRead permite consultar los enlaces propios y sus estadísticas.
+	ScopeRead Scope = "read"
+	// ScopeManage permite modificar y borrar los enlaces propios.
+	ScopeManage Scope = "manage"
+	// ScopeAdmin incluye el resto de permisos sobre los enlaces de cualquier propietario.
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes son todos los permisos válidos.
+var Scopes = []Scope{ScopeCreate, ScopeRead, ScopeManage, ScopeAdmin}
+
+// ParseScopes interpreta una lista de permisos separados por comas.
+func ParseScopes(value string) ([]Scope, error) {
+	var scopes []Scope
+	for _, field := range strings.Split(value, ",") {
+		scope := Scope(strings.TrimSpace(field))
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// tokenPrefix identifica los tokens de este servicio, p. ej. en escáneres de secretos.
+const tokenPrefix = "urli_"
+
+// Key es una clave de API tal como se guarda: solo el hash del secreto.
+type Key struct {
+	// ID identifica la clave y forma parte del token, así que no es secreto.
+	ID string `json:"id"`
+	// Owner es el propietario de los enlaces creados con la clave. Varias
+	// claves pueden compartir propietario.
+	Owner  string  `json:"owner"`
+	Scopes []Scope `json:"scopes"`
+	// Hash es el SHA-256 en hexadecimal del secreto.
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+	// RevokedAt es cuándo se revocó la clave; cero si sigue activa.
+	RevokedAt time.Time `json:"revoked_at,omitzero"`
+}
+
+// Has indica si la clave tiene el permiso scope. ScopeAdmin los incluye todos.
+func (k Key) Has(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// Revoked indica si la clave fue revocada.
+func (k Key) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// newKey genera una clave nueva y devuelve también su token, que no se guarda.
+func newKey(owner string, scopes []Scope, now time.Time) (Key, string, error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, "", err
+	}
+
+	key := Key{
+		ID:        hex.EncodeToString(id),
+		Owner:     owner,
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if key.Owner == "" {
+		key.Owner = key.ID
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = hashSecret(encoded)
+	return key, tokenPrefix + key.ID + "_" + encoded, nil
+}
+
+// parseToken separa un token en el ID de la clave y el secreto. El ID es
+// hexadecimal, así que el primer "_" tras el prefijo es el separador.
+func parseToken(token string) (id, secret string, ok bool) {
+	rest, found := strings.CutPrefix(token, tokenPrefix)
+	if !found {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(rest, "_")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// matches compara el secreto con el hash guardado en tiempo constante.
+func (k Key) matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.Hash)) == 1
+}
+
+type contextKey struct{}
+
+// NewContext devuelve una copia de ctx con la clave autenticada.
+func NewContext(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// FromContext devuelve la clave autenticada de ctx, si la hay.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(contextKey{}).(Key)
+	return key, ok
+}
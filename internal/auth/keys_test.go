@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("create, read,create")
+	if err != nil || !reflect.DeepEqual(scopes, []Scope{ScopeCreate, ScopeRead}) {
+		t.Errorf("Expected [create read], got %v (err=%v)", scopes, err)
+	}
+	for _, value := range []string{"", " , ", "create,delete"} {
+		if _, err := ParseScopes(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestKey_Has(t *testing.T) {
+	key := Key{Scopes: []Scope{ScopeCreate}}
+	if !key.Has(ScopeCreate) || key.Has(ScopeManage) {
+		t.Error("Expected only the create scope")
+	}
+	admin := Key{Scopes: []Scope{ScopeAdmin}}
+	for _, scope := range Scopes {
+		if !admin.Has(scope) {
+			t.Errorf("Expected admin to include %s", scope)
+		}
+	}
+}
+
+func TestNewKey_Token(t *testing.T) {
+	key, token, err := newKey("", []Scope{ScopeRead}, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix+key.ID+"_") {
+		t.Errorf("Expected token to embed the key ID, got %s", token)
+	}
+	if key.Owner != key.ID {
+		t.Errorf("Expected empty owner to default to the ID, got %q", key.Owner)
+	}
+	if strings.Contains(key.Hash, token) {
+		t.Error("Expected the token not to be stored")
+	}
+
+	id, secret, ok := parseToken(token)
+	if !ok || id != key.ID || !key.matches(secret) {
+		t.Errorf("Expected token to match its key (id=%q ok=%v)", id, ok)
+	}
+	if key.matches(secret + "x") {
+		t.Error("Expected a different secret not to match")
+	}
+
+	for _, bad := range []string{"", "abc", tokenPrefix, tokenPrefix + "id", tokenPrefix + "_secret"} {
+		if _, _, ok := parseToken(bad); ok {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("Expected no key in an empty context")
+	}
+	ctx := NewContext(context.Background(), Key{ID: "abc"})
+	if key, ok := FromContext(ctx); !ok || key.ID != "abc" {
+		t.Errorf("Expected key abc, got %+v", key)
+	}
+}
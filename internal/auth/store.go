@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrInvalidKey se devuelve cuando el token no corresponde a ninguna clave.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrRevokedKey se devuelve cuando la clave del token fue revocada.
+	ErrRevokedKey = errors.New("API key revoked")
+	// ErrKeyNotFound se devuelve al revocar una clave que no existe.
+	ErrKeyNotFound = errors.New("API key not found")
+)
+
+// refreshInterval es cada cuánto se comprueba, como mucho, si el fichero
+// cambió. Así las claves creadas o revocadas con el CLI se aplican sin
+// reiniciar el servidor.
+const refreshInterval = time.Second
+
+// Store es el almacén de claves de API, guardado como JSON en un fichero. Es
+// seguro para uso concurrente dentro de un proceso; entre procesos (el
+// servidor y el CLI) el fichero se reemplaza de forma atómica.
+type Store struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]Key
+	// modTime y size identifican la versión cargada del fichero.
+	modTime time.Time
+	size    int64
+	// nextCheck es el instante (UnixNano) a partir del cual refresh vuelve a
+	// mirar el fichero. Es atómico para que Authenticate no tenga que tomar
+	// el cerrojo exclusivo en cada petición.
+	nextCheck atomic.Int64
+}
+
+// storeFile es el formato del fichero de claves.
+type storeFile struct {
+	Keys []Key `json:"keys"`
+}
+
+// Open carga el almacén de path. Un fichero inexistente es un almacén vacío
+// que se creará al guardar la primera clave.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, keys: make(map[string]Key)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load lee el fichero. Debe llamarse con s.mu tomado.
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.keys = make(map[string]Key)
+		s.modTime, s.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading key store: %w", err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing key store %s: %w", s.path, err)
+	}
+	keys := make(map[string]Key, len(file.Keys))
+	for _, key := range file.Keys {
+		keys[key.ID] = key
+	}
+	s.keys = keys
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+// refresh recarga el fichero si cambió desde la última carga, comprobándolo
+// como mucho una vez por refreshInterval. Solo la goroutine que reclama la
+// comprobación mira el fichero, y el cerrojo exclusivo se toma únicamente si
+// hay que recargar. Si falla, se conservan las claves anteriores.
+func (s *Store) refresh() {
+	now := time.Now().UnixNano()
+	next := s.nextCheck.Load()
+	if now < next || !s.nextCheck.CompareAndSwap(next, now+int64(refreshInterval)) {
+		return
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return
+	}
+	s.mu.RLock()
+	unchanged := info.ModTime().Equal(s.modTime) && info.Size() == s.size
+	s.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		log.Printf("Auth: error recargando las claves, se conservan las anteriores: %v", err)
+	}
+}
+
+// Authenticate devuelve la clave del token. Devuelve ErrInvalidKey si el
+// token no es válido y ErrRevokedKey si la clave fue revocada.
+func (s *Store) Authenticate(token string) (Key, error) {
+	s.refresh()
+
+	id, secret, ok := parseToken(token)
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+	s.mu.RLock()
+	key, exists := s.keys[id]
+	s.mu.RUnlock()
+	if !exists || !key.matches(secret) {
+		return Key{}, ErrInvalidKey
+	}
+	if key.Revoked() {
+		return Key{}, ErrRevokedKey
+	}
+	return key, nil
+}
+
+// Mint crea una clave para owner con los permisos indicados y la guarda.
+// Devuelve el token, que solo se conoce en este momento. Un owner vacío usa
+// el ID de la clave.
+func (s *Store) Mint(owner string, scopes []Scope) (Key, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Releer antes de escribir para no pisar cambios de otro proceso
+	if err := s.load(); err != nil {
+		return Key{}, "", err
+	}
+
+	key, token, err := newKey(owner, scopes, time.Now().UTC())
+	if err != nil {
+		return Key{}, "", fmt.Errorf("generating API key: %w", err)
+	}
+	s.keys[key.ID] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		return Key{}, "", err
+	}
+	return key, token, nil
+}
+
+// Revoke revoca la clave id. Revocar una clave ya revocada no hace nada.
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	key, exists := s.keys[id]
+	if !exists {
+		return fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	if key.Revoked() {
+		return nil
+	}
+	key.RevokedAt = time.Now().UTC()
+	s.keys[id] = key
+	return s.save()
+}
+
+// List devuelve las claves ordenadas por fecha de creación.
+func (s *Store) List() []Key {
+	s.refresh()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted()
+}
+
+// sorted devuelve las claves ordenadas. Debe llamarse con s.mu tomado.
+func (s *Store) sorted() []Key {
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// save escribe el fichero en uno temporal y lo renombra, para que el
+// servidor nunca lea uno a medias. Debe llamarse con s.mu tomado.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(storeFile{Keys: s.sorted()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing key store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	// Los hashes no son secretos, pero no hay motivo para que otros los lean
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing key store: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing key store: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing key store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing key store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("writing key store: %w", err)
+	}
+
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
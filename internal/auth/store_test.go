@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error opening key store: %v", err)
+	}
+	return store, path
+}
+
+func TestStore_MintAndAuthenticate(t *testing.T) {
+	store, path := openTestStore(t)
+
+	key, token, err := store.Mint("marketing", []Scope{ScopeCreate, ScopeRead})
+	if err != nil {
+		t.Fatalf("Error minting key: %v", err)
+	}
+
+	got, err := store.Authenticate(token)
+	if err != nil || got.ID != key.ID || got.Owner != "marketing" {
+		t.Errorf("Expected key %s, got %+v (err=%v)", key.ID, got, err)
+	}
+	if _, err := store.Authenticate(token + "x"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+	if _, err := store.Authenticate("Bearer " + token); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey, got %v", err)
+	}
+
+	// El fichero guarda el hash, nunca el token
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), token[len(tokenPrefix)+len(key.ID)+1:]) {
+		t.Error("Expected the secret not to be written to disk")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// Otro proceso que abra el fichero ve la misma clave
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Error reopening key store: %v", err)
+	}
+	if _, err := reopened.Authenticate(token); err != nil {
+		t.Errorf("Expected key to survive reopening, got %v", err)
+	}
+}
+
+func TestStore_Revoke(t *testing.T) {
+	store, _ := openTestStore(t)
+	key, token, _ := store.Mint("", []Scope{ScopeRead})
+
+	if err := store.Revoke(key.ID); err != nil {
+		t.Fatalf("Error revoking key: %v", err)
+	}
+	if _, err := store.Authenticate(token); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("Expected ErrRevokedKey, got %v", err)
+	}
+	if err := store.Revoke(key.ID); err != nil {
+		t.Errorf("Expected revoking twice to be a no-op, got %v", err)
+	}
+	if err := store.Revoke("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestStore_PicksUpChangesFromOtherProcesses(t *testing.T) {
+	server, path := openTestStore(t)
+	cli, _ := Open(path)
+
+	key, token, _ := cli.Mint("", []Scope{ScopeCreate})
+	server.nextCheck.Store(0)
+	if _, err := server.Authenticate(token); err != nil {
+		t.Fatalf("Expected server to see the new key, got %v", err)
+	}
+
+	cli.Revoke(key.ID)
+	server.nextCheck.Store(0)
+	if _, err := server.Authenticate(token); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("Expected server to see the revocation, got %v", err)
+	}
+}
+
+func TestStore_AuthenticateSharesTheLock(t *testing.T) {
+	store, _ := openTestStore(t)
+	_, token, _ := store.Mint("", []Scope{ScopeRead})
+	store.Authenticate(token)
+
+	// Con otra lectura en curso, Authenticate no debe esperar: si no toca
+	// recargar, no necesita el cerrojo exclusivo.
+	store.mu.RLock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Authenticate(token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the key to authenticate, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected Authenticate not to wait for the exclusive lock")
+		store.mu.RUnlock()
+		<-done
+		return
+	}
+	store.mu.RUnlock()
+}
+
+func TestStore_List(t *testing.T) {
+	store, _ := openTestStore(t)
+	first, _, _ := store.Mint("a", []Scope{ScopeRead})
+	second, _, _ := store.Mint("b", []Scope{ScopeAdmin})
+
+	keys := store.List()
+	if len(keys) != 2 {
+		t.Fatalf("Expected 2 keys, got %d", len(keys))
+	}
+	ids := map[string]bool{keys[0].ID: true, keys[1].ID: true}
+	if !ids[first.ID] || !ids[second.ID] {
+		t.Errorf("Expected both keys to be listed, got %+v", keys)
+	}
+}
+
+func TestOpen_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(path, []byte("{not json"), 0o600)
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for a corrupt key store")
+	}
+}
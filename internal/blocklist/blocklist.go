@@ -0,0 +1,148 @@
+package blocklist
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Blocklist carga las reglas desde un fichero y las recarga cuando cambia.
+// Es seguro para uso concurrente.
+type Blocklist struct {
+	path     string
+	interval time.Duration
+
+	rules atomic.Pointer[Rules]
+
+	// reloadMu protege modTime y size, que identifican la versión cargada.
+	reloadMu sync.Mutex
+	modTime  time.Time
+	size     int64
+
+	// pending es la última versión observada y aún no cargada, y rejected la
+	// última que no se pudo cargar. Solo los usa la goroutine de recarga.
+	pending  os.FileInfo
+	rejected os.FileInfo
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New carga las reglas de path. interval es cada cuánto se comprueba si el
+// fichero cambió una vez llamado Start.
+func New(path string, interval time.Duration) (*Blocklist, error) {
+	b := &Blocklist{path: path, interval: interval}
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Check consulta el destino rawURL con las reglas vigentes.
+func (b *Blocklist) Check(rawURL string) Verdict {
+	return b.rules.Load().Check(rawURL)
+}
+
+// Reload vuelve a leer el fichero. Si falla, se conservan las reglas anteriores.
+func (b *Blocklist) Reload() error {
+	b.reloadMu.Lock()
+	defer b.reloadMu.Unlock()
+
+	f, err := os.Open(b.path)
+	if err != nil {
+		return fmt.Errorf("opening blocklist: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("opening blocklist: %w", err)
+	}
+	rules, err := Parse(f)
+	if err != nil {
+		return fmt.Errorf("parsing blocklist %s: %w", b.path, err)
+	}
+
+	b.rules.Store(rules)
+	b.modTime, b.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// Start lanza la goroutine que recarga el fichero al cambiar. Llamarlo con
+// la recarga ya activa no hace nada.
+func (b *Blocklist) Start() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stop != nil || b.interval <= 0 {
+		return
+	}
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.run(b.stop, b.done)
+}
+
+// Stop detiene la recarga y espera a que termine.
+func (b *Blocklist) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stop == nil {
+		return
+	}
+	close(b.stop)
+	<-b.done
+	b.stop, b.done = nil, nil
+}
+
+func (b *Blocklist) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.reloadIfChanged()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// reloadIfChanged recarga el fichero si su fecha de modificación o tamaño
+// cambió. Espera a ver la misma versión en dos comprobaciones seguidas para
+// no cargar un fichero a medio escribir.
+func (b *Blocklist) reloadIfChanged() {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		log.Printf("Error comprobando la blocklist: %v", err)
+		return
+	}
+	b.reloadMu.Lock()
+	unchanged := info.ModTime().Equal(b.modTime) && info.Size() == b.size
+	b.reloadMu.Unlock()
+	if unchanged || sameVersion(info, b.rejected) {
+		b.pending = nil
+		return
+	}
+
+	stable := sameVersion(info, b.pending)
+	b.pending = info
+	if !stable {
+		return
+	}
+	if err := b.Reload(); err != nil {
+		b.rejected = info
+		log.Printf("Error recargando la blocklist (se mantienen las reglas anteriores): %v", err)
+		return
+	}
+	log.Printf("Blocklist recargada: %d reglas", b.rules.Load().Len())
+}
+
+func sameVersion(info, other os.FileInfo) bool {
+	return other != nil && info.ModTime().Equal(other.ModTime()) && info.Size() == other.Size()
+}
@@ -0,0 +1,72 @@
+package blocklist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeBlocklist(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Expected no error writing blocklist, got %v", err)
+	}
+}
+
+func TestBlocklist_New(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "evil.com\n")
+
+	b, err := New(path, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !b.Check("https://evil.com").Blocked {
+		t.Error("Expected evil.com to be blocked")
+	}
+
+	if _, err := New(filepath.Join(t.TempDir(), "missing.txt"), 0); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestBlocklist_HotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "evil.com\n")
+
+	b, err := New(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	b.Start()
+	defer b.Stop()
+
+	writeBlocklist(t, path, "evil.com\nworse.com\n")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !b.Check("https://worse.com").Blocked {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected blocklist to be reloaded after the file changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Un fichero inválido no reemplaza las reglas vigentes
+	writeBlocklist(t, path, "re:(broken\n")
+	time.Sleep(50 * time.Millisecond)
+	if !b.Check("https://worse.com").Blocked {
+		t.Error("Expected previous rules to be kept after a failed reload")
+	}
+}
+
+func TestBlocklist_StartStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	writeBlocklist(t, path, "")
+
+	b, _ := New(path, time.Millisecond)
+	b.Start()
+	b.Start()
+	b.Stop()
+	b.Stop()
+}
@@ -0,0 +1,216 @@
+// Package blocklist decide qué destinos no deben acortarse ni redirigirse.
+package blocklist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackparradev/url-inteligente/internal/util"
+)
+
+// Verdict es el resultado de consultar un destino.
+type Verdict struct {
+	// Blocked indica que el destino está bloqueado.
+	Blocked bool
+	// Rule es la regla que decidió el resultado, tal como aparece en el fichero.
+	Rule string
+}
+
+// hostsFileNames son las entradas estándar de un fichero hosts que no deben
+// tratarse como dominios bloqueados.
+var hostsFileNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// ruleSet agrupa las reglas de un mismo tipo (bloqueo o excepción).
+type ruleSet struct {
+	exact    map[string]string
+	suffixes map[string]string
+	paths    []pathRule
+}
+
+type pathRule struct {
+	re   *regexp.Regexp
+	rule string
+}
+
+func newRuleSet() ruleSet {
+	return ruleSet{exact: map[string]string{}, suffixes: map[string]string{}}
+}
+
+// match devuelve la regla que cubre host o target ("host/ruta"), si hay alguna.
+func (s *ruleSet) match(host, target string) (string, bool) {
+	if rule, ok := s.exact[host]; ok {
+		return rule, true
+	}
+	// Recorrer los sufijos de host: a.b.example.com, b.example.com, example.com...
+	for suffix := host; suffix != ""; {
+		if rule, ok := s.suffixes[suffix]; ok {
+			return rule, true
+		}
+		_, rest, found := strings.Cut(suffix, ".")
+		if !found {
+			break
+		}
+		suffix = rest
+	}
+	for _, p := range s.paths {
+		if p.re.MatchString(target) {
+			return p.rule, true
+		}
+	}
+	return "", false
+}
+
+// Rules es un conjunto inmutable de reglas. Cada línea del fichero es una de:
+//
+//	evil.com              host exacto
+//	*.evil.com            evil.com y todos sus subdominios
+//	re:^evil\.com/login   expresión regular sobre "host/ruta"
+//	0.0.0.0 a.com b.com   formato de fichero hosts (IP seguida de hosts)
+//	!good.evil.com        excepción: permite lo que otra regla bloquearía
+//
+// Las líneas vacías y los comentarios ("#" al inicio o tras un espacio) se ignoran.
+type Rules struct {
+	deny  ruleSet
+	allow ruleSet
+}
+
+// Parse lee reglas de r.
+func Parse(r io.Reader) (*Rules, error) {
+	rules := &Rules{deny: newRuleSet(), allow: newRuleSet()}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if err := rules.add(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *Rules) add(line string) error {
+	set := &r.deny
+	rule := line
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		set = &r.allow
+		line = strings.TrimSpace(rest)
+	}
+
+	if pattern, ok := strings.CutPrefix(line, "re:"); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		set.paths = append(set.paths, pathRule{re: re, rule: rule})
+		return nil
+	}
+
+	fields := strings.Fields(line)
+	// Formato hosts: la primera columna es una IP y el resto son nombres
+	if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+		for _, name := range fields[1:] {
+			if hostsFileNames[strings.ToLower(name)] {
+				continue
+			}
+			host, err := canonicalHost(name)
+			if err != nil {
+				return err
+			}
+			set.exact[host] = rule
+		}
+		return nil
+	}
+	if len(fields) != 1 {
+		return fmt.Errorf("invalid rule %q", line)
+	}
+
+	if suffix, ok := strings.CutPrefix(line, "*."); ok {
+		host, err := canonicalHost(suffix)
+		if err != nil {
+			return err
+		}
+		set.suffixes[host] = rule
+		return nil
+	}
+
+	host, err := canonicalHost(line)
+	if err != nil {
+		return err
+	}
+	set.exact[host] = rule
+	return nil
+}
+
+// Check consulta el destino rawURL. Las excepciones tienen prioridad sobre
+// las reglas de bloqueo. Una URL que no puede interpretarse no se bloquea;
+// rechazarla es tarea de la validación.
+func (r *Rules) Check(rawURL string) Verdict {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Verdict{}
+	}
+	host, err := canonicalHost(u.Hostname())
+	if err != nil || host == "" {
+		return Verdict{}
+	}
+	target := host + u.EscapedPath()
+
+	if rule, ok := r.allow.match(host, target); ok {
+		return Verdict{Rule: rule}
+	}
+	if rule, ok := r.deny.match(host, target); ok {
+		return Verdict{Blocked: true, Rule: rule}
+	}
+	return Verdict{}
+}
+
+// Len devuelve el número de reglas cargadas.
+func (r *Rules) Len() int {
+	return len(r.deny.exact) + len(r.deny.suffixes) + len(r.deny.paths) +
+		len(r.allow.exact) + len(r.allow.suffixes) + len(r.allow.paths)
+}
+
+// stripComment elimina un comentario "#" al inicio de la línea o precedido de
+// un espacio, para no romper expresiones regulares que contengan "#".
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// canonicalHost pasa host a minúsculas y Punycode y quita el punto final.
+func canonicalHost(host string) (string, error) {
+	host = strings.TrimSuffix(host, ".")
+	ascii, err := util.HostToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %w", host, err)
+	}
+	return ascii, nil
+}
@@ -0,0 +1,91 @@
+package blocklist
+
+import (
+	"strings"
+	"testing"
+)
+
+const testRules = `
+# Lista de prueba
+evil.com
+*.phish.net
+re:^docs\.example\.com/login
+re:/wp-admin(/|$)
+
+# Formato hosts
+0.0.0.0 tracker.io ads.tracker.io
+127.0.0.1 localhost
+::1 ip6-localhost
+
+# Excepciones
+!safe.phish.net
+!re:^docs\.example\.com/login/help
+Bücher.example
+`
+
+func TestRules_Check(t *testing.T) {
+	rules, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		url     string
+		blocked bool
+		rule    string
+	}{
+		{"https://evil.com/anything", true, "evil.com"},
+		{"https://EVIL.com.", true, "evil.com"},
+		{"https://sub.evil.com", false, ""},
+		{"https://phish.net", true, "*.phish.net"},
+		{"https://a.b.phish.net/x", true, "*.phish.net"},
+		{"https://notphish.net", false, ""},
+		{"https://safe.phish.net", false, "!safe.phish.net"},
+		{"https://docs.example.com/login", true, `re:^docs\.example\.com/login`},
+		{"https://docs.example.com/login/help", false, `!re:^docs\.example\.com/login/help`},
+		{"https://docs.example.com/", false, ""},
+		{"https://blog.com/wp-admin/", true, "re:/wp-admin(/|$)"},
+		{"https://tracker.io", true, "0.0.0.0 tracker.io ads.tracker.io"},
+		{"https://ads.tracker.io", true, "0.0.0.0 tracker.io ads.tracker.io"},
+		{"http://localhost:8080", false, ""},
+		{"https://xn--bcher-kva.example", true, "Bücher.example"},
+		{"https://google.com", false, ""},
+		{"not a url", false, ""},
+	}
+
+	for _, test := range tests {
+		verdict := rules.Check(test.url)
+		if verdict.Blocked != test.blocked || verdict.Rule != test.rule {
+			t.Errorf("Check(%q) = %+v; expected blocked=%v rule=%q", test.url, verdict, test.blocked, test.rule)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	invalid := []string{
+		"re:(unclosed",
+		"two words",
+		"ok.com\n!re:[",
+	}
+
+	for _, input := range invalid {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+
+	_, err := Parse(strings.NewReader("ok.com\nre:(unclosed"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error to mention line 2, got %v", err)
+	}
+}
+
+func TestParse_CommentsInRegex(t *testing.T) {
+	rules, err := Parse(strings.NewReader(`re:/page#anchor # comentario`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rules.Len() != 1 {
+		t.Errorf("Expected 1 rule, got %d", rules.Len())
+	}
+}
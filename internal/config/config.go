@@ -1,23 +1,268 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
 // Config contiene los parámetros de configuración de la aplicación.
 type Config struct {
 	// ServerPort es el puerto donde corre el servidor (incluye el prefijo ':').
 	ServerPort string
+	// ReadHeaderTimeout es el tiempo máximo para leer las cabeceras de una petición.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout es el tiempo máximo para leer una petición completa, o cada
+	// tanda de un lote (0 = sin límite).
+	ReadTimeout time.Duration
+	// WriteTimeout es el tiempo máximo para escribir una respuesta, o cada
+	// tanda de un lote (0 = sin límite).
+	WriteTimeout time.Duration
+	// IdleTimeout es cuánto se mantiene abierta una conexión keep-alive inactiva.
+	IdleTimeout time.Duration
+	// ShutdownTimeout es cuánto se espera al apagar a que terminen las
+	// peticiones en curso y, después, a que se detenga cada componente.
+	ShutdownTimeout time.Duration
+	// ShutdownDelay es cuánto se sigue atendiendo tras la señal de apagado,
+	// con /readyz fallando, antes de dejar de aceptar conexiones.
+	ShutdownDelay time.Duration
 	// BaseURL es la URL base usada para generar los enlaces cortos (sin barra final).
 	BaseURL string
+	// Domains son las URLs base de dominios cortos adicionales. Cada dominio
+	// tiene su propio espacio de códigos; BaseURL es el dominio por defecto.
+	Domains []string
 	// MaxRetry es el número máximo de intentos para generar un código único.
 	MaxRetry int
 	// ShortCodeLength es la longitud del código corto generado.
 	ShortCodeLength int
+	// StorageBackend es el backend de almacenamiento a usar ("memory", "sharded" o "file").
+	StorageBackend string
+	// StorageShards es el número de shards del backend "sharded" (0 = cuatro por procesador).
+	StorageShards int
+	// StorageDir es el directorio de datos del backend "file".
+	StorageDir string
+	// FsyncPolicy es la política de sincronización del log ("always", "interval" o "never").
+	FsyncPolicy string
+	// FsyncInterval es cada cuánto se sincroniza el log con la política "interval".
+	FsyncInterval time.Duration
+	// SnapshotInterval es cada cuánto se escribe un snapshot y se compacta el log (0 lo desactiva).
+	SnapshotInterval time.Duration
+	// ReaperInterval es cada cuánto se purgan los enlaces expirados.
+	ReaperInterval time.Duration
+	// AliasCharset son los caracteres permitidos en alias personalizados.
+	AliasCharset string
+	// AliasMinLength y AliasMaxLength acotan la longitud de un alias.
+	AliasMinLength int
+	AliasMaxLength int
+	// ReservedWords son los nombres que nunca pueden usarse como código corto.
+	ReservedWords []string
+	// Generator es la estrategia de generación de códigos ("random", "hash", "counter" u "obfuscated").
+	Generator string
+	// GeneratorKey es la clave de la permutación del generador "obfuscated" (0 = aleatoria).
+	GeneratorKey uint64
+	// Dedup reutiliza el código existente al acortar una URL ya vista.
+	Dedup bool
+	// TrackingParams son los parámetros de query que se eliminan al normalizar URLs ("*" final = prefijo).
+	TrackingParams []string
+	// AllowedSchemes son los esquemas de URL que pueden acortarse.
+	AllowedSchemes []string
+	// MaxURLLength es la longitud máxima de una URL en bytes (0 = sin límite).
+	MaxURLLength int
+	// AllowUserinfo acepta URLs con credenciales.
+	AllowUserinfo bool
+	// AllowIPLiterals acepta URLs cuyo host es una dirección IP.
+	AllowIPLiterals bool
+	// AllowPrivateNetworks acepta destinos en redes privadas y localhost.
+	AllowPrivateNetworks bool
+	// AllowedPorts restringe los puertos explícitos de las URLs (vacío = cualquiera).
+	AllowedPorts []int
+	// BlocklistFile es el fichero de reglas de destinos bloqueados (vacío = sin blocklist).
+	BlocklistFile string
+	// BlocklistReloadInterval es cada cuánto se comprueba si el fichero de blocklist cambió.
+	BlocklistReloadInterval time.Duration
+	// Analytics activa el registro de clics y el endpoint de estadísticas.
+	Analytics bool
+	// AnalyticsBufferSize es la capacidad de la cola de clics; con la cola llena se descartan.
+	AnalyticsBufferSize int
+	// AnalyticsSalt es la clave con la que se anonimizan las IPs (vacía = aleatoria en cada arranque).
+	AnalyticsSalt string
+	// BatchMaxSize es el máximo de elementos por petición de acortado en lote.
+	BatchMaxSize int
+	// KeysFile es el almacén de claves de API. Vacío desactiva la autenticación.
+	KeysFile string
+	// DefaultRedirectType es el código de redirección de los enlaces que no
+	// eligen uno (301, 302, 307 o 308).
+	DefaultRedirectType int
+	// RedirectCacheMaxAge es el max-age de las redirecciones permanentes (301 y 308).
+	RedirectCacheMaxAge time.Duration
+	// CreateRateLimit es el ritmo de creación de enlaces permitido a cada
+	// cliente (clave de API o IP), en peticiones por segundo (0 = sin límite).
+	CreateRateLimit float64
+	// CreateRateBurst es la ráfaga de creaciones permitida por encima del ritmo.
+	CreateRateBurst int
+	// BatchRateLimit es el ritmo de elementos de lote permitido a cada
+	// cliente, en elementos por segundo (0 = sin límite). Un lote paga además
+	// una petición del límite de creación.
+	BatchRateLimit float64
+	// BatchRateBurst es la ráfaga de elementos de lote permitida por encima del
+	// ritmo; por defecto cabe un lote completo.
+	BatchRateBurst int
+	// RedirectRateLimit es el ritmo de redirecciones permitido a cada IP, en
+	// peticiones por segundo (0 = sin límite).
+	RedirectRateLimit float64
+	// RedirectRateBurst es la ráfaga de redirecciones permitida por encima del ritmo.
+	RedirectRateBurst int
+	// RateLimitSweepInterval es cada cuánto se descartan los límites de clientes inactivos.
+	RateLimitSweepInterval time.Duration
+	// LogFormat es el formato de los logs ("json" o "text", que es logfmt).
+	LogFormat string
+	// LogLevel es el nivel mínimo de los logs ("debug", "info", "warn" o "error").
+	LogLevel string
+	// Metrics expone las métricas en formato Prometheus en GET /metrics.
+	Metrics bool
+	// MaxLinks es el máximo de enlaces almacenados (0 = sin límite).
+	MaxLinks int
+	// MaxStorageBytes es el máximo aproximado de memoria de los enlaces en bytes (0 = sin límite).
+	MaxStorageBytes int64
+	// EvictionPolicy es qué hacer al alcanzar MaxLinks o MaxStorageBytes
+	// ("reject", "lru" u "oldest").
+	EvictionPolicy string
 }
 
-// Get devuelve un puntero a Config con valores predefinidos.
-func Get() *Config {
+// Default devuelve la configuración por defecto, la base sobre la que Load
+// aplica el fichero, las variables de entorno y los flags.
+func Default() *Config {
+	// Los valores por defecto de los alias son los del servicio
+	aliases := service.DefaultAliasPolicy()
 	return &Config{
-		ServerPort:      ":8080",
-		BaseURL:         "http://localhost:8080",
-		MaxRetry:        5,
-		ShortCodeLength: 6,
+		ServerPort:       ":8080",
+		BaseURL:          "http://localhost:8080",
+		MaxRetry:         service.DefaultMaxAttempts,
+		ShortCodeLength:  service.DefaultShortCodeLength,
+		StorageBackend:   "memory",
+		StorageDir:       "data",
+		FsyncPolicy:      "interval",
+		FsyncInterval:    time.Second,
+		SnapshotInterval: 5 * time.Minute,
+		ReaperInterval:   time.Minute,
+		AliasCharset:     aliases.Charset,
+		AliasMinLength:   aliases.MinLength,
+		AliasMaxLength:   aliases.MaxLength,
+		ReservedWords:    slices.Clone(aliases.Reserved),
+		Generator:        "random",
+		TrackingParams:   []string{"utm_*", "fbclid", "gclid"},
+		AllowedSchemes:   []string{"http", "https"},
+		MaxURLLength:     2048,
+		// Permitido por defecto para que localhost funcione en desarrollo
+		AllowPrivateNetworks:    true,
+		BlocklistReloadInterval: 10 * time.Second,
+		Analytics:               true,
+		AnalyticsBufferSize:     4096,
+		BatchMaxSize:            10000,
+		// 302 para que los enlaces editables no queden cacheados
+		DefaultRedirectType: 302,
+		RedirectCacheMaxAge: 24 * time.Hour,
+		// Holgado para uso normal; frena a un cliente que inunde el servidor
+		CreateRateLimit:        5,
+		CreateRateBurst:        50,
+		BatchRateLimit:         1000,
+		BatchRateBurst:         10000,
+		RedirectRateLimit:      50,
+		RedirectRateBurst:      200,
+		RateLimitSweepInterval: time.Minute,
+		EvictionPolicy:         "reject",
+		Metrics:                true,
+		LogFormat:              "json",
+		LogLevel:               "info",
+		// Acotan a los clientes lentos sin cortar los lotes grandes
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   15 * time.Second,
+	}
+}
+
+// Validate comprueba que la configuración es coherente y devuelve todos los
+// problemas encontrados a la vez.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	_, port, err := net.SplitHostPort(c.ServerPort)
+	check(err == nil && port != "", "server-port: invalid address %q", c.ServerPort)
+	base, err := url.Parse(c.BaseURL)
+	check(err == nil && (base.Scheme == "http" || base.Scheme == "https") && base.Host != "",
+		"base-url: must be an absolute http(s) URL, got %q", c.BaseURL)
+	hosts := map[string]bool{}
+	if base != nil {
+		hosts[strings.ToLower(base.Host)] = true
 	}
+	for _, domain := range c.Domains {
+		u, err := url.Parse(domain)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			check(false, "domains: must be absolute http(s) URLs, got %q", domain)
+			continue
+		}
+		check(!hosts[strings.ToLower(u.Host)], "domains: duplicate host %q", u.Host)
+		hosts[strings.ToLower(u.Host)] = true
+	}
+	check(c.MaxRetry > 0, "max-retry: must be positive, got %d", c.MaxRetry)
+	check(c.ShortCodeLength > 0, "short-code-length: must be positive, got %d", c.ShortCodeLength)
+
+	check(slices.Contains([]string{"memory", "sharded", "file"}, c.StorageBackend), "storage: unknown backend %q", c.StorageBackend)
+	check(c.StorageShards >= 0, "storage-shards: must not be negative, got %d", c.StorageShards)
+	check(c.StorageBackend != "file" || c.StorageDir != "", "storage-dir: required by the file backend")
+	check(slices.Contains([]string{"always", "interval", "never"}, c.FsyncPolicy), "fsync: unknown policy %q", c.FsyncPolicy)
+	check(c.FsyncInterval > 0, "fsync-interval: must be positive, got %s", c.FsyncInterval)
+	check(c.SnapshotInterval >= 0, "snapshot-interval: must not be negative, got %s", c.SnapshotInterval)
+	check(c.ReaperInterval > 0, "reaper-interval: must be positive, got %s", c.ReaperInterval)
+
+	check(c.AliasCharset != "", "alias-charset: must not be empty")
+	check(!strings.Contains(c.AliasCharset, "/"), "alias-charset: must not contain '/'")
+	check(c.AliasMinLength > 0 && c.AliasMinLength <= c.AliasMaxLength,
+		"alias-min-length/alias-max-length: invalid range %d-%d", c.AliasMinLength, c.AliasMaxLength)
+	check(slices.Contains([]string{"random", "hash", "counter", "obfuscated"}, c.Generator), "generator: unknown strategy %q", c.Generator)
+
+	check(len(c.AllowedSchemes) > 0, "allowed-schemes: must not be empty")
+	check(c.MaxURLLength >= 0, "max-url-length: must not be negative, got %d", c.MaxURLLength)
+	for _, port := range c.AllowedPorts {
+		check(port > 0 && port <= 65535, "allowed-ports: invalid port %d", port)
+	}
+	check(c.BlocklistReloadInterval > 0, "blocklist-reload: must be positive, got %s", c.BlocklistReloadInterval)
+	check(c.AnalyticsBufferSize > 0, "analytics-buffer: must be positive, got %d", c.AnalyticsBufferSize)
+	check(c.BatchMaxSize > 0, "batch-max-size: must be positive, got %d", c.BatchMaxSize)
+	check(slices.Contains([]int{301, 302, 307, 308}, c.DefaultRedirectType),
+		"redirect-type: must be 301, 302, 307 or 308, got %d", c.DefaultRedirectType)
+	check(c.RedirectCacheMaxAge >= 0, "redirect-max-age: must not be negative, got %s", c.RedirectCacheMaxAge)
+	check(c.CreateRateLimit >= 0, "create-rate: must not be negative, got %g", c.CreateRateLimit)
+	check(c.CreateRateBurst > 0, "create-burst: must be positive, got %d", c.CreateRateBurst)
+	check(c.BatchRateLimit >= 0, "batch-rate: must not be negative, got %g", c.BatchRateLimit)
+	check(c.BatchRateBurst > 0, "batch-burst: must be positive, got %d", c.BatchRateBurst)
+	check(c.RedirectRateLimit >= 0, "redirect-rate: must not be negative, got %g", c.RedirectRateLimit)
+	check(c.RedirectRateBurst > 0, "redirect-burst: must be positive, got %d", c.RedirectRateBurst)
+	check(slices.Contains([]string{"json", "text"}, c.LogFormat), "log-format: unknown format %q", c.LogFormat)
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, strings.ToLower(c.LogLevel)), "log-level: unknown level %q", c.LogLevel)
+	check(c.MaxLinks >= 0, "max-links: must not be negative, got %d", c.MaxLinks)
+	check(c.MaxStorageBytes >= 0, "max-storage-bytes: must not be negative, got %d", c.MaxStorageBytes)
+	check(slices.Contains([]string{"reject", "lru", "oldest"}, c.EvictionPolicy), "eviction: unknown policy %q", c.EvictionPolicy)
+	check(c.RateLimitSweepInterval > 0, "rate-limit-sweep: must be positive, got %s", c.RateLimitSweepInterval)
+	check(c.ReadHeaderTimeout > 0, "read-header-timeout: must be positive, got %s", c.ReadHeaderTimeout)
+	check(c.ReadTimeout >= 0, "read-timeout: must not be negative, got %s", c.ReadTimeout)
+	check(c.WriteTimeout >= 0, "write-timeout: must not be negative, got %s", c.WriteTimeout)
+	check(c.IdleTimeout >= 0, "idle-timeout: must not be negative, got %s", c.IdleTimeout)
+	check(c.ShutdownTimeout > 0, "shutdown-timeout: must be positive, got %s", c.ShutdownTimeout)
+	check(c.ShutdownDelay >= 0, "shutdown-delay: must not be negative, got %s", c.ShutdownDelay)
+
+	return errors.Join(errs...)
 }
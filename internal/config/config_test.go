@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Expected defaults to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"bad port", func(c *Config) { c.ServerPort = "8080" }, "server-port"},
+		{"relative base url", func(c *Config) { c.BaseURL = "sho.rt" }, "base-url"},
+		{"relative domain", func(c *Config) { c.Domains = []string{"acme.link"} }, "domains"},
+		{"duplicate domain", func(c *Config) { c.Domains = []string{"https://localhost:8080"} }, "duplicate host"},
+		{"zero retries", func(c *Config) { c.MaxRetry = 0 }, "max-retry"},
+		{"zero code length", func(c *Config) { c.ShortCodeLength = 0 }, "short-code-length"},
+		{"unknown backend", func(c *Config) { c.StorageBackend = "redis" }, "storage"},
+		{"file without dir", func(c *Config) { c.StorageBackend, c.StorageDir = "file", "" }, "storage-dir"},
+		{"slash in alias charset", func(c *Config) { c.AliasCharset += "/" }, "alias-charset"},
+		{"alias range", func(c *Config) { c.AliasMinLength = 10; c.AliasMaxLength = 5 }, "alias-min-length"},
+		{"unknown generator", func(c *Config) { c.Generator = "uuid" }, "generator"},
+		{"bad allowed port", func(c *Config) { c.AllowedPorts = []int{70000} }, "allowed-ports"},
+		{"redirect type", func(c *Config) { c.DefaultRedirectType = 303 }, "redirect-type"},
+		{"negative create rate", func(c *Config) { c.CreateRateLimit = -1 }, "create-rate"},
+		{"zero batch burst", func(c *Config) { c.BatchRateBurst = 0 }, "batch-burst"},
+		{"zero redirect burst", func(c *Config) { c.RedirectRateBurst = 0 }, "redirect-burst"},
+		{"unknown log format", func(c *Config) { c.LogFormat = "xml" }, "log-format"},
+		{"unknown log level", func(c *Config) { c.LogLevel = "trace" }, "log-level"},
+		{"unknown eviction", func(c *Config) { c.EvictionPolicy = "lfu" }, "eviction"},
+		{"negative write timeout", func(c *Config) { c.WriteTimeout = -time.Second }, "write-timeout"},
+		{"zero shutdown timeout", func(c *Config) { c.ShutdownTimeout = 0 }, "shutdown-timeout"},
+		{"negative shutdown delay", func(c *Config) { c.ShutdownDelay = -time.Second }, "shutdown-delay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.MaxRetry = 0
+	cfg.BatchMaxSize = 0
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "max-retry") || !strings.Contains(err.Error(), "batch-max-size") {
+		t.Errorf("Expected both errors, got %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix es el prefijo de las variables de entorno: el flag -base-url se
+// lee de URLI_BASE_URL.
+const EnvPrefix = "URLI_"
+
+// Load construye la configuración combinando, de menor a mayor prioridad, los
+// valores por defecto, el fichero de configuración (-config o URLI_CONFIG), las
+// variables de entorno y los flags de args. Devuelve flag.ErrHelp si se pidió
+// la ayuda y un error si algún valor es inválido.
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	var path string
+	fs := cfg.flagSet(&path)
+
+	// El fichero se aplica antes que el resto, así que su ruta se busca a mano
+	path, _ = lookupEnv(envName("config"))
+	if fromArgs, ok := configFlag(args); ok {
+		path = fromArgs
+	}
+	if path != "" {
+		if err := applyFile(fs, path); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok || f.Name == "config" || err != nil {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", envName(f.Name), setErr)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// flagSet registra un flag por cada campo de c. El nombre del flag es también
+// la clave en el fichero y, con EnvPrefix, la variable de entorno.
+func (c *Config) flagSet(path *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("url-inteligente", flag.ContinueOnError)
+	fs.StringVar(path, "config", "", "fichero de configuración (JSON o clave = valor)")
+
+	fs.Func("server-port", "dirección de escucha, p. ej. :8080 o 127.0.0.1:8080 (por defecto "+c.ServerPort+")", func(value string) error {
+		if !strings.Contains(value, ":") {
+			value = ":" + value
+		}
+		c.ServerPort = value
+		return nil
+	})
+	fs.DurationVar(&c.ReadHeaderTimeout, "read-header-timeout", c.ReadHeaderTimeout, "tiempo máximo para leer las cabeceras de una petición")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "tiempo máximo para leer una petición completa (0 = sin límite)")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "tiempo máximo para escribir una respuesta (0 = sin límite)")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "tiempo máximo de una conexión keep-alive inactiva")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "espera máxima al apagar para las peticiones en curso y cada componente")
+	fs.DurationVar(&c.ShutdownDelay, "shutdown-delay", c.ShutdownDelay, "tiempo que se sigue atendiendo tras la señal, con /readyz fallando, antes de drenar")
+	fs.StringVar(&c.BaseURL, "base-url", c.BaseURL, "URL base pública de los enlaces cortos")
+	fs.Func("domains", "URLs base de dominios cortos adicionales, separadas por comas", func(value string) error {
+		c.Domains = splitList(value)
+		return nil
+	})
+	fs.IntVar(&c.MaxRetry, "max-retry", c.MaxRetry, "intentos máximos para generar un código único")
+	fs.IntVar(&c.ShortCodeLength, "short-code-length", c.ShortCodeLength, "longitud de los códigos generados")
+
+	fs.StringVar(&c.StorageBackend, "storage", c.StorageBackend, "backend de almacenamiento (memory, sharded, file)")
+	fs.IntVar(&c.StorageShards, "storage-shards", c.StorageShards, "shards del backend sharded (0 = cuatro por procesador)")
+	fs.StringVar(&c.StorageDir, "storage-dir", c.StorageDir, "directorio de datos del backend file")
+	fs.StringVar(&c.FsyncPolicy, "fsync", c.FsyncPolicy, "política de fsync del log (always, interval, never)")
+	fs.DurationVar(&c.FsyncInterval, "fsync-interval", c.FsyncInterval, "intervalo de fsync con -fsync=interval")
+	fs.DurationVar(&c.SnapshotInterval, "snapshot-interval", c.SnapshotInterval, "intervalo de snapshots y compactación del log (0 los desactiva)")
+	fs.DurationVar(&c.ReaperInterval, "reaper-interval", c.ReaperInterval, "intervalo de purga de enlaces expirados")
+	fs.StringVar(&c.LogFormat, "log-format", c.LogFormat, "formato de los logs (json, text)")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "nivel mínimo de los logs (debug, info, warn, error)")
+	fs.BoolVar(&c.Metrics, "metrics", c.Metrics, "exponer métricas Prometheus en /metrics")
+	fs.IntVar(&c.MaxLinks, "max-links", c.MaxLinks, "máximo de enlaces almacenados (0 = sin límite)")
+	fs.Int64Var(&c.MaxStorageBytes, "max-storage-bytes", c.MaxStorageBytes, "memoria aproximada máxima de los enlaces en bytes (0 = sin límite)")
+	fs.StringVar(&c.EvictionPolicy, "eviction", c.EvictionPolicy, "política con el almacenamiento lleno (reject, lru, oldest)")
+
+	fs.StringVar(&c.AliasCharset, "alias-charset", c.AliasCharset, "caracteres permitidos en alias personalizados")
+	fs.IntVar(&c.AliasMinLength, "alias-min-length", c.AliasMinLength, "longitud mínima de un alias")
+	fs.IntVar(&c.AliasMaxLength, "alias-max-length", c.AliasMaxLength, "longitud máxima de un alias")
+	fs.Func("reserved-words", "palabras reservadas separadas por comas", func(value string) error {
+		c.ReservedWords = splitList(value)
+		return nil
+	})
+	fs.StringVar(&c.Generator, "generator", c.Generator, "estrategia de generación de códigos (random, hash, counter, obfuscated)")
+	fs.Uint64Var(&c.GeneratorKey, "generator-key", c.GeneratorKey, "clave del generador obfuscated (0 = aleatoria)")
+	fs.BoolVar(&c.Dedup, "dedup", c.Dedup, "reutilizar el código existente para URLs repetidas")
+
+	fs.Func("tracking-params", "parámetros de seguimiento a eliminar, separados por comas (\"*\" final = prefijo)", func(value string) error {
+		c.TrackingParams = splitList(value)
+		return nil
+	})
+	fs.Func("allowed-schemes", "esquemas de URL permitidos, separados por comas", func(value string) error {
+		c.AllowedSchemes = splitList(value)
+		return nil
+	})
+	fs.IntVar(&c.MaxURLLength, "max-url-length", c.MaxURLLength, "longitud máxima de una URL en bytes (0 = sin límite)")
+	fs.BoolVar(&c.AllowUserinfo, "allow-userinfo", c.AllowUserinfo, "aceptar URLs con credenciales")
+	fs.BoolVar(&c.AllowIPLiterals, "allow-ip-literals", c.AllowIPLiterals, "aceptar URLs cuyo host es una IP")
+	fs.BoolVar(&c.AllowPrivateNetworks, "allow-private-networks", c.AllowPrivateNetworks, "aceptar destinos en redes privadas y localhost")
+	fs.Func("allowed-ports", "puertos explícitos permitidos, separados por comas (vacío = cualquiera)", func(value string) error {
+		var ports []int
+		for _, field := range splitList(value) {
+			port, err := strconv.Atoi(field)
+			if err != nil {
+				return fmt.Errorf("invalid port %q", field)
+			}
+			ports = append(ports, port)
+		}
+		c.AllowedPorts = ports
+		return nil
+	})
+
+	fs.StringVar(&c.BlocklistFile, "blocklist", c.BlocklistFile, "fichero de reglas de destinos bloqueados")
+	fs.DurationVar(&c.BlocklistReloadInterval, "blocklist-reload", c.BlocklistReloadInterval, "intervalo de comprobación de cambios en la blocklist")
+	fs.BoolVar(&c.Analytics, "analytics", c.Analytics, "registrar clics y exponer estadísticas")
+	fs.IntVar(&c.AnalyticsBufferSize, "analytics-buffer", c.AnalyticsBufferSize, "capacidad de la cola de clics")
+	fs.StringVar(&c.AnalyticsSalt, "analytics-salt", c.AnalyticsSalt, "clave para anonimizar IPs (vacía = aleatoria)")
+	fs.StringVar(&c.KeysFile, "keys-file", c.KeysFile, "almacén de claves de API (vacío = sin autenticación)")
+	fs.IntVar(&c.BatchMaxSize, "batch-max-size", c.BatchMaxSize, "máximo de elementos por petición de acortado en lote")
+	fs.IntVar(&c.DefaultRedirectType, "redirect-type", c.DefaultRedirectType, "código de redirección por defecto (301, 302, 307 o 308)")
+	fs.DurationVar(&c.RedirectCacheMaxAge, "redirect-max-age", c.RedirectCacheMaxAge, "max-age de las redirecciones permanentes")
+	fs.Float64Var(&c.CreateRateLimit, "create-rate", c.CreateRateLimit, "creaciones por segundo permitidas a cada cliente (0 = sin límite)")
+	fs.IntVar(&c.CreateRateBurst, "create-burst", c.CreateRateBurst, "ráfaga de creaciones permitida a cada cliente")
+	fs.Float64Var(&c.BatchRateLimit, "batch-rate", c.BatchRateLimit, "elementos de lote por segundo permitidos a cada cliente (0 = sin límite)")
+	fs.IntVar(&c.BatchRateBurst, "batch-burst", c.BatchRateBurst, "ráfaga de elementos de lote permitida a cada cliente")
+	fs.Float64Var(&c.RedirectRateLimit, "redirect-rate", c.RedirectRateLimit, "redirecciones por segundo permitidas a cada IP (0 = sin límite)")
+	fs.IntVar(&c.RedirectRateBurst, "redirect-burst", c.RedirectRateBurst, "ráfaga de redirecciones permitida a cada IP")
+	fs.DurationVar(&c.RateLimitSweepInterval, "rate-limit-sweep", c.RateLimitSweepInterval, "intervalo de limpieza de los límites de clientes inactivos")
+	return fs
+}
+
+// envName devuelve la variable de entorno de un flag: base-url -> URLI_BASE_URL.
+func envName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// configFlag busca -config en args con las mismas reglas que el paquete flag:
+// se detiene en el primer argumento que no es un flag o en "--".
+func configFlag(args []string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// splitList separa una lista por comas descartando espacios y elementos vacíos.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// applyFile aplica el fichero de configuración sobre fs. Admite un objeto JSON
+// o líneas "clave = valor" al estilo TOML, con comentarios "#", valores entre
+// comillas y listas ["a", "b"]. Las claves son los nombres de los flags; "_"
+// equivale a "-".
+func applyFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var entries []fileEntry
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		entries, err = parseJSON(trimmed)
+	} else {
+		entries, err = parseKeyValue(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for _, entry := range entries {
+		key := strings.ReplaceAll(strings.ToLower(entry.key), "_", "-")
+		if key == "config" || fs.Lookup(key) == nil {
+			return fmt.Errorf("%s:%s: unknown key %q", path, entry.where, entry.key)
+		}
+		if err := fs.Set(key, entry.value); err != nil {
+			return fmt.Errorf("%s:%s: %s: %w", path, entry.where, entry.key, err)
+		}
+	}
+	return nil
+}
+
+// fileEntry es un par clave/valor del fichero; where indica su posición para
+// los mensajes de error.
+type fileEntry struct {
+	key   string
+	value string
+	where string
+}
+
+func parseJSON(data []byte) ([]fileEntry, error) {
+	var object map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	entries := make([]fileEntry, 0, len(object))
+	for key, raw := range object {
+		value, err := jsonValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		entries = append(entries, fileEntry{key: key, value: value, where: key})
+	}
+	return entries, nil
+}
+
+// jsonValue convierte un valor JSON a la forma textual que acepta el flag.
+func jsonValue(raw any) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			value, err := jsonValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = value
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", raw)
+	}
+}
+
+func parseKeyValue(data []byte) ([]fileEntry, error) {
+	var entries []fileEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, raw, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+		}
+		value, err := parseValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		entries = append(entries, fileEntry{key: strings.TrimSpace(key), value: value, where: strconv.Itoa(lineNum)})
+	}
+	return entries, scanner.Err()
+}
+
+// parseValue interpreta un valor: una cadena entre comillas, una lista entre
+// corchetes o un valor sin comillas, que termina en el primer " #".
+func parseValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, rest, err := unquotePrefix(raw)
+		if err != nil {
+			return "", err
+		}
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			return "", fmt.Errorf("unexpected %q after string", rest)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "["):
+		return parseList(raw[1:])
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// parseList interpreta los elementos de una lista tras el "[" inicial.
+func parseList(raw string) (string, error) {
+	var items []string
+	for {
+		raw = strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(raw, "]"):
+			if rest := strings.TrimSpace(raw[1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", fmt.Errorf("unexpected %q after list", rest)
+			}
+			return strings.Join(items, ","), nil
+		case raw == "":
+			return "", fmt.Errorf("unterminated list")
+		case strings.HasPrefix(raw, `"`):
+			value, rest, err := unquotePrefix(raw)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+			raw = rest
+		default:
+			end := strings.IndexAny(raw, ",]")
+			if end < 0 {
+				return "", fmt.Errorf("unterminated list")
+			}
+			items = append(items, strings.TrimSpace(raw[:end]))
+			raw = raw[end:]
+		}
+		raw = strings.TrimSpace(raw)
+		raw = strings.TrimPrefix(raw, ",")
+	}
+}
+
+// unquotePrefix extrae la cadena entre comillas al principio de raw y devuelve
+// el resto sin espacios iniciales.
+func unquotePrefix(raw string) (string, string, error) {
+	prefix, err := strconv.QuotedPrefix(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid string %s", raw)
+	}
+	value, _ := strconv.Unquote(prefix)
+	return value, strings.TrimSpace(raw[len(prefix):]), nil
+}
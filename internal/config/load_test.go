@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envMap(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(nil, envMap(nil))
+	if err != nil {
+		t.Fatalf("Expected defaults to be valid, got %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+# Fichero de ejemplo
+server_port = 9000
+base-url = "https://sho.rt"   # comentario
+max-retry = 3
+reserved-words = ["shorten", "api", "login"]
+`)
+	env := envMap(map[string]string{
+		"URLI_CONFIG":    path,
+		"URLI_MAX_RETRY": "8",
+		"URLI_DEDUP":     "true",
+	})
+
+	cfg, err := load([]string{"-max-retry", "10", "-fsync-interval=2s"}, env)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.ServerPort != ":9000" || cfg.BaseURL != "https://sho.rt" {
+		t.Errorf("Expected values from file, got %q %q", cfg.ServerPort, cfg.BaseURL)
+	}
+	if !reflect.DeepEqual(cfg.ReservedWords, []string{"shorten", "api", "login"}) {
+		t.Errorf("Expected list from file, got %v", cfg.ReservedWords)
+	}
+	if !cfg.Dedup {
+		t.Error("Expected dedup from environment")
+	}
+	// Los flags ganan al entorno, y el entorno al fichero
+	if cfg.MaxRetry != 10 {
+		t.Errorf("Expected max-retry 10 from flags, got %d", cfg.MaxRetry)
+	}
+	if cfg.FsyncInterval != 2*time.Second {
+		t.Errorf("Expected fsync-interval 2s, got %s", cfg.FsyncInterval)
+	}
+
+	cfg, _ = load(nil, env)
+	if cfg.MaxRetry != 8 {
+		t.Errorf("Expected max-retry 8 from environment, got %d", cfg.MaxRetry)
+	}
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{
+		"base_url": "https://sho.rt/",
+		"short-code-length": 9,
+		"allowed_ports": [443, 8443],
+		"dedup": true,
+		"redirect-max-age": "1h"
+	}`)
+
+	cfg, err := load([]string{"-config", path}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.BaseURL != "https://sho.rt/" || cfg.ShortCodeLength != 9 || !cfg.Dedup || cfg.RedirectCacheMaxAge != time.Hour {
+		t.Errorf("Expected values from JSON, got %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.AllowedPorts, []int{443, 8443}) {
+		t.Errorf("Expected ports [443 8443], got %v", cfg.AllowedPorts)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "unknown key", file: "colour = blue", wantErr: `:1: unknown key "colour"`},
+		{name: "bad value in file", file: "\nmax-retry = many", wantErr: ":2: max-retry"},
+		{name: "malformed line", file: "max-retry", wantErr: "line 1"},
+		{name: "unterminated list", file: `reserved-words = ["a", "b"`, wantErr: "unterminated list"},
+		{name: "bad env", env: map[string]string{"URLI_DEDUP": "maybe"}, wantErr: "URLI_DEDUP"},
+		{name: "bad flag", args: []string{"-max-retry", "x"}, wantErr: "max-retry"},
+		{name: "extra argument", args: []string{"serve"}, wantErr: `unexpected argument "serve"`},
+		{name: "invalid config", args: []string{"-max-retry", "0", "-redirect-type", "303"}, wantErr: "redirect-type"},
+		{name: "missing file", args: []string{"-config", "/nonexistent/config.toml"}, wantErr: "reading config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, "config.toml", tt.file)}, args...)
+			}
+			_, err := load(args, envMap(tt.env))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	fs := Default().flagSet(new(string))
+	fs.SetOutput(new(strings.Builder))
+	if err := fs.Parse([]string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected flag.ErrHelp, got %v", err)
+	}
+}
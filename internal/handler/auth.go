@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+// WithAuth exige una clave de API de keys en las rutas envueltas con Require.
+// Sin esta opción la autenticación está desactivada y todo está permitido.
+func WithAuth(keys *auth.Store) HandlerOption {
+	return func(h *Handler) {
+		h.auth = keys
+	}
+}
+
+// Require envuelve next para que exija una clave de API con el permiso scope
+// en la cabecera "Authorization: Bearer <token>". La clave queda en el
+// contexto de la petición. Con la autenticación desactivada devuelve next.
+func (h *Handler) Require(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	if h.auth == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="url-inteligente"`)
+			respondWithError(w, "API key required", http.StatusUnauthorized)
+			return
+		}
+
+		key, err := h.auth.Authenticate(strings.TrimSpace(token))
+		if err != nil {
+			message := "Invalid API key"
+			if errors.Is(err, auth.ErrRevokedKey) {
+				message = "API key revoked"
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="url-inteligente", error="invalid_token"`)
+			respondWithError(w, message, http.StatusUnauthorized)
+			return
+		}
+		if !key.Has(scope) {
+			respondWithError(w, "API key lacks the "+string(scope)+" scope", http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(auth.NewContext(r.Context(), key)))
+	}
+}
+
+// owner devuelve el propietario de los enlaces que cree la petición: el de
+// su clave de API, o vacío sin autenticación.
+func (h *Handler) owner(r *http.Request) string {
+	key, _ := auth.FromContext(r.Context())
+	return key.Owner
+}
+
+// seesAllLinks indica si la petición puede ver y modificar enlaces de
+// cualquier propietario: sin autenticación o con el permiso admin.
+func (h *Handler) seesAllLinks(r *http.Request) bool {
+	if h.auth == nil {
+		return true
+	}
+	key, ok := auth.FromContext(r.Context())
+	return ok && key.Has(auth.ScopeAdmin)
+}
+
+// canAccess indica si la petición puede ver y modificar link. Los enlaces
+// ajenos se tratan como inexistentes para no revelar qué códigos están en uso.
+func (h *Handler) canAccess(r *http.Request, link service.Link) bool {
+	return h.seesAllLinks(r) || link.Owner == h.owner(r)
+}
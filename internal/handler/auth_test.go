@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+// newAuthMux registra las rutas con los mismos permisos que main.
+func newAuthMux(t *testing.T) (*http.ServeMux, *auth.Store) {
+	t.Helper()
+	keys, err := auth.Open(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("Error opening key store: %v", err)
+	}
+	h := NewHandler(service.NewShortener(service.NewStorage()), WithAuth(keys))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", h.Require(auth.ScopeCreate, h.ShortenURL))
+	mux.HandleFunc("GET /api/v1/links", h.Require(auth.ScopeRead, h.ListLinks))
+	mux.HandleFunc("GET /api/v1/links/{code}", h.Require(auth.ScopeRead, h.GetLink))
+	mux.HandleFunc("PATCH /api/v1/links/{code}", h.Require(auth.ScopeManage, h.UpdateLink))
+	mux.HandleFunc("DELETE /api/v1/links/{code}", h.Require(auth.ScopeManage, h.DeleteLink))
+	mux.HandleFunc("/", h.RedirectURL)
+	return mux, keys
+}
+
+func mintToken(t *testing.T, keys *auth.Store, owner string, scopes ...auth.Scope) string {
+	t.Helper()
+	_, token, err := keys.Mint(owner, scopes)
+	if err != nil {
+		t.Fatalf("Error minting key: %v", err)
+	}
+	return token
+}
+
+// withToken hace que serve envíe la petición con la clave de API token.
+func withToken(token string) func(*http.Request) {
+	return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+}
+
+func TestHandler_Require(t *testing.T) {
+	mux, keys := newAuthMux(t)
+	reader := mintToken(t, keys, "alice", auth.ScopeRead)
+	creator := mintToken(t, keys, "alice", auth.ScopeCreate)
+	body := ShortenRequest{URL: "https://www.google.com"}
+
+	rr := serve(mux, http.MethodPost, "/shorten", body)
+	if rr.Code != http.StatusUnauthorized || rr.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("Expected 401 with WWW-Authenticate without a key, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/shorten", body, withToken("urli_bad_token")); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an invalid key, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/shorten", body, withToken(reader)); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 without the create scope, got %d", rr.Code)
+	}
+
+	rr = serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.google.com", Alias: "promo"}, withToken(creator))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 with the create scope, got %d", rr.Code)
+	}
+
+	// Las redirecciones no exigen clave
+	if rr := serve(mux, http.MethodGet, "/promo", nil); rr.Code != http.StatusFound {
+		t.Errorf("Expected public redirect, got %d", rr.Code)
+	}
+}
+
+func TestHandler_Require_RevokedKey(t *testing.T) {
+	mux, keys := newAuthMux(t)
+	key, token, _ := keys.Mint("alice", []auth.Scope{auth.ScopeCreate})
+	keys.Revoke(key.ID)
+
+	var response ErrorResponse
+	rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.google.com"}, withToken(token))
+	json.NewDecoder(rr.Body).Decode(&response)
+	if rr.Code != http.StatusUnauthorized || response.Error != "API key revoked" {
+		t.Errorf("Expected 401 API key revoked, got %d %+v", rr.Code, response)
+	}
+}
+
+func TestHandler_Ownership(t *testing.T) {
+	mux, keys := newAuthMux(t)
+	alice := mintToken(t, keys, "alice", auth.ScopeCreate, auth.ScopeRead, auth.ScopeManage)
+	bob := mintToken(t, keys, "bob", auth.ScopeCreate, auth.ScopeRead, auth.ScopeManage)
+	admin := mintToken(t, keys, "ops", auth.ScopeAdmin)
+
+	serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://alice.com", Alias: "alice-link"}, withToken(alice))
+	serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://bob.com", Alias: "bob-link"}, withToken(bob))
+
+	rr := serve(mux, http.MethodGet, "/api/v1/links/alice-link", nil, withToken(alice))
+	var link LinkResponse
+	json.NewDecoder(rr.Body).Decode(&link)
+	if rr.Code != http.StatusOK || link.Owner != "alice" {
+		t.Errorf("Expected alice to see her link, got %d %+v", rr.Code, link)
+	}
+
+	// Los enlaces ajenos no existen para bob
+	if rr := serve(mux, http.MethodGet, "/api/v1/links/alice-link", nil, withToken(bob)); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another owner's link, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPatch, "/api/v1/links/alice-link", UpdateLinkRequest{URL: "https://evil.com"}, withToken(bob)); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 updating another owner's link, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodDelete, "/api/v1/links/alice-link", nil, withToken(bob)); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting another owner's link, got %d", rr.Code)
+	}
+
+	var list LinkListResponse
+	json.NewDecoder(serve(mux, http.MethodGet, "/api/v1/links", nil, withToken(bob)).Body).Decode(&list)
+	if len(list.Links) != 1 || list.Links[0].Code != "bob-link" {
+		t.Errorf("Expected bob to list only his link, got %+v", list.Links)
+	}
+	json.NewDecoder(serve(mux, http.MethodGet, "/api/v1/links", nil, withToken(admin)).Body).Decode(&list)
+	if len(list.Links) != 2 {
+		t.Errorf("Expected admin to list every link, got %+v", list.Links)
+	}
+
+	if rr := serve(mux, http.MethodDelete, "/api/v1/links/alice-link", nil, withToken(admin)); rr.Code != http.StatusNoContent {
+		t.Errorf("Expected admin to delete any link, got %d", rr.Code)
+	}
+}
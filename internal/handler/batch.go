@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/logging"
+)
+
+const (
+	// DefaultMaxBatchSize es el máximo de elementos por lote por defecto.
+	DefaultMaxBatchSize = 10000
+
+	// batchFlushEvery es cada cuántos resultados se envían al cliente.
+	batchFlushEvery = 100
+
+	// maxBatchItemBytes es el tamaño máximo de un elemento del lote. El cuerpo
+	// entero se limita a este tamaño por el máximo de elementos, así que ni una
+	// línea enorme ni un flujo sin fin hacen crecer la memoria sin límite.
+	maxBatchItemBytes = 64 << 10
+)
+
+// BatchResult es el resultado de un elemento del lote. Lleva los campos de
+// ShortenResponse si tuvo éxito o los de ErrorResponse si falló.
+type BatchResult struct {
+	// Index es la posición del elemento en la entrada, empezando en 0.
+	Index int `json:"index"`
+	// Status es el código HTTP que habría devuelto POST /shorten.
+	Status int `json:"status"`
+	*ShortenResponse
+	*ErrorResponse
+}
+
+// WithBatchTimeouts hace que los lotes amplíen los plazos de lectura y
+// escritura de la conexión en read y write en cada tanda de resultados que
+// envían. Así los timeouts del servidor, que deben ser los mismos, acotan a
+// un cliente lento en cada tanda sin cortar un lote largo que avanza. Cero
+// deja ese plazo como esté.
+func WithBatchTimeouts(read, write time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.batchReadTimeout = read
+		h.batchWriteTimeout = write
+	}
+}
+
+// batchWriter escribe los resultados en el mismo formato que la entrada.
+type batchWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	ndjson  bool
+	written int
+	// readTimeout y writeTimeout son los plazos que se dan a cada tanda.
+	readTimeout, writeTimeout time.Duration
+}
+
+func (h *Handler) newBatchWriter(w http.ResponseWriter, ndjson bool) *batchWriter {
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(http.StatusOK)
+	if !ndjson {
+		io.WriteString(w, "[")
+	}
+	b := &batchWriter{
+		w:            w,
+		rc:           http.NewResponseController(w),
+		enc:          json.NewEncoder(w),
+		ndjson:       ndjson,
+		readTimeout:  h.batchReadTimeout,
+		writeTimeout: h.batchWriteTimeout,
+	}
+	b.extendDeadlines()
+	return b
+}
+
+// extendDeadlines da a la siguiente tanda sus propios plazos. Los errores se
+// ignoran: no todos los ResponseWriter admiten plazos.
+func (b *batchWriter) extendDeadlines() {
+	now := time.Now()
+	if b.readTimeout > 0 {
+		b.rc.SetReadDeadline(now.Add(b.readTimeout))
+	}
+	if b.writeTimeout > 0 {
+		b.rc.SetWriteDeadline(now.Add(b.writeTimeout))
+	}
+}
+
+func (b *batchWriter) write(result BatchResult) {
+	if !b.ndjson && b.written > 0 {
+		io.WriteString(b.w, ",")
+	}
+	b.enc.Encode(result)
+	b.written++
+	if b.written%batchFlushEvery == 0 {
+		b.rc.Flush()
+		b.extendDeadlines()
+	}
+}
+
+func (b *batchWriter) close() {
+	if !b.ndjson {
+		io.WriteString(b.w, "]\n")
+	}
+	b.rc.Flush()
+}
+
+// ShortenBatch acorta varias URLs en una petición (POST /api/v1/shorten/batch).
+// Acepta un array JSON de ShortenRequest o un flujo NDJSON (uno por línea) y
+// responde en el mismo formato con un BatchResult por elemento, a medida que
+// se procesan. Un elemento inválido no hace fallar al resto.
+func (h *Handler) ShortenBatch(w http.ResponseWriter, r *http.Request) {
+	// Sin esto, en HTTP/1 el servidor cierra el cuerpo que quede por leer en
+	// cuanto se envía la primera parte de la respuesta
+	http.NewResponseController(w).EnableFullDuplex()
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, int64(h.maxBatchSize)*maxBatchItemBytes))
+
+	// El primer carácter distinto de espacio decide el formato
+	first, err := peekNonSpace(body)
+	if err == io.EOF {
+		respondWithError(w, "Empty batch", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	// Los elementos sin domain usan el dominio de la cabecera Host
+	defaults := batchDefaults{domain: h.requestDomain(r), owner: h.owner(r), client: rateLimitKey(r)}
+	if first == '[' {
+		h.shortenJSONArray(r.Context(), w, body, defaults)
+	} else {
+		h.shortenNDJSON(r.Context(), w, body, defaults)
+	}
+}
+
+// batchDefaults son los valores de la petición comunes a todos sus elementos.
+type batchDefaults struct {
+	// domain es el dominio de la cabecera Host.
+	domain string
+	// owner es el propietario de la clave de API de la petición.
+	owner string
+	// client identifica a quien hace la petición en el límite de creación.
+	client string
+}
+
+func (h *Handler) shortenJSONArray(ctx context.Context, w http.ResponseWriter, body io.Reader, defaults batchDefaults) {
+	dec := json.NewDecoder(body)
+	dec.Token() // '['
+
+	out := h.newBatchWriter(w, false)
+	defer out.close()
+
+	for index := 0; dec.More(); index++ {
+		if index >= h.maxBatchSize {
+			out.write(batchSizeExceeded(ctx, index, h.maxBatchSize))
+			return
+		}
+		// Decodificar primero a RawMessage separa los errores de sintaxis,
+		// que impiden seguir leyendo, de los de un elemento concreto
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			out.write(batchReadError(ctx, index, err, "Invalid JSON"))
+			return
+		}
+		if len(raw) > maxBatchItemBytes {
+			out.write(batchItemTooLarge(ctx, index))
+			continue
+		}
+		out.write(h.shortenBatchItem(ctx, index, raw, defaults))
+	}
+}
+
+func (h *Handler) shortenNDJSON(ctx context.Context, w http.ResponseWriter, body *bufio.Reader, defaults batchDefaults) {
+	out := h.newBatchWriter(w, true)
+	defer out.close()
+
+	// Una línea más larga que el búfer detiene el escáner con bufio.ErrTooLong
+	lines := bufio.NewScanner(body)
+	lines.Buffer(make([]byte, 0, 4096), maxBatchItemBytes)
+	index := 0
+	for lines.Scan() {
+		line := bytes.TrimSpace(lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if index >= h.maxBatchSize {
+			out.write(batchSizeExceeded(ctx, index, h.maxBatchSize))
+			return
+		}
+		out.write(h.shortenBatchItem(ctx, index, line, defaults))
+		index++
+	}
+	if err := lines.Err(); err != nil {
+		out.write(batchReadError(ctx, index, err, "Error reading request body"))
+	}
+}
+
+// shortenBatchItem procesa un elemento igual que POST /shorten.
+func (h *Handler) shortenBatchItem(ctx context.Context, index int, data []byte, defaults batchDefaults) BatchResult {
+	if !h.allowBatchItem(defaults.client) {
+		return batchError(ctx, index, ErrorResponse{Error: "Rate limit exceeded"}, http.StatusTooManyRequests)
+	}
+	var req ShortenRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return batchError(ctx, index, ErrorResponse{Error: "Invalid JSON"}, http.StatusBadRequest)
+	}
+
+	domain, ok := h.shortenDomain(defaults.domain, req.Domain)
+	if !ok {
+		return batchError(ctx, index, ErrorResponse{Error: "Unknown domain"}, http.StatusBadRequest)
+	}
+	opts, err := req.createOptions()
+	if err != nil {
+		response, code := serviceError(err, "Error creating short URL")
+		return batchError(ctx, index, response, code)
+	}
+	opts.Domain = domain
+	opts.Owner = defaults.owner
+
+	link, created, err := h.shortener.CreateContext(ctx, req.URL, opts)
+	if err != nil {
+		response, code := serviceError(err, "Error creating short URL")
+		return batchError(ctx, index, response, code)
+	}
+
+	response := h.shortenResponse(link)
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	return BatchResult{Index: index, Status: status, ShortenResponse: &response}
+}
+
+// batchError es el resultado fallido del elemento index. Lleva el
+// identificador de la petición, como las respuestas de error sueltas.
+func batchError(ctx context.Context, index int, response ErrorResponse, code int) BatchResult {
+	response.RequestID = logging.RequestID(ctx)
+	return BatchResult{Index: index, Status: code, ErrorResponse: &response}
+}
+
+func batchSizeExceeded(ctx context.Context, index, max int) BatchResult {
+	return batchError(ctx, index, ErrorResponse{Error: fmt.Sprintf("batch exceeds the maximum of %d items", max)}, http.StatusRequestEntityTooLarge)
+}
+
+func batchItemTooLarge(ctx context.Context, index int) BatchResult {
+	return batchError(ctx, index, ErrorResponse{Error: fmt.Sprintf("batch item exceeds %d bytes", maxBatchItemBytes)}, http.StatusRequestEntityTooLarge)
+}
+
+// batchReadError es el resultado que corta el lote al fallar la lectura del
+// elemento index: 413 si se superó un límite de tamaño y 400 con message si no.
+func batchReadError(ctx context.Context, index int, err error, message string) BatchResult {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, bufio.ErrTooLong):
+		return batchItemTooLarge(ctx, index)
+	case errors.As(err, &tooLarge):
+		return batchError(ctx, index, ErrorResponse{Error: fmt.Sprintf("batch exceeds %d bytes", tooLarge.Limit)}, http.StatusRequestEntityTooLarge)
+	default:
+		return batchError(ctx, index, ErrorResponse{Error: message}, http.StatusBadRequest)
+	}
+}
+
+// peekNonSpace descarta los espacios iniciales y devuelve el siguiente byte
+// sin consumirlo.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, r.UnreadByte()
+	}
+}
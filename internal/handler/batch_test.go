@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/logging"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+func shortenBatch(h *Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten/batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.ShortenBatch(rr, req)
+	return rr
+}
+
+func TestHandler_ShortenBatch_JSONArray(t *testing.T) {
+	storage := service.NewStorage()
+	handler := NewHandler(service.NewShortener(storage))
+
+	rr := shortenBatch(handler, `[
+		{"url": "https://www.google.com"},
+		{"url": "ftp://invalid.com"},
+		42,
+		{"url": "https://www.github.com", "alias": "gh"},
+		{"url": "https://www.github.com", "alias": "github"},
+		{"url": "https://www.example.com", "alias": "github"}
+	]`)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected application/json, got %s", contentType)
+	}
+
+	var results []BatchResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("Expected a JSON array, got error %v", err)
+	}
+
+	expected := []int{
+		http.StatusCreated,
+		http.StatusBadRequest,
+		http.StatusBadRequest,
+		http.StatusBadRequest,
+		http.StatusCreated,
+		http.StatusConflict,
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if result.Index != i || result.Status != expected[i] {
+			t.Errorf("Expected index %d with status %d, got %+v", i, expected[i], result)
+		}
+	}
+
+	if results[0].ShortenResponse == nil || results[0].LongURL != "https://www.google.com" {
+		t.Errorf("Expected success fields in first result, got %+v", results[0])
+	}
+	if results[1].ErrorResponse == nil || results[1].Reason != "bad_scheme" {
+		t.Errorf("Expected bad_scheme reason in second result, got %+v", results[1])
+	}
+	if storage.Count() != 2 {
+		t.Errorf("Expected 2 links stored, got %d", storage.Count())
+	}
+}
+
+func TestHandler_ShortenBatch_NDJSON(t *testing.T) {
+	handler := NewHandler(service.NewShortener(service.NewStorage()))
+
+	rr := shortenBatch(handler, "{\"url\": \"https://a.com\"}\n\n{not json}\n{\"url\": \"https://b.com\"}")
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("Expected application/x-ndjson, got %s", contentType)
+	}
+
+	var statuses []int
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Expected one JSON object per line, got %q", scanner.Text())
+		}
+		statuses = append(statuses, result.Status)
+	}
+
+	if fmt.Sprint(statuses) != "[201 400 201]" {
+		t.Errorf("Expected statuses [201 400 201], got %v", statuses)
+	}
+}
+
+func TestHandler_ShortenBatch_MaxSize(t *testing.T) {
+	storage := service.NewStorage()
+	handler := NewHandler(service.NewShortener(storage), WithMaxBatchSize(2))
+
+	rr := shortenBatch(handler, `[{"url": "https://a.com"}, {"url": "https://b.com"}, {"url": "https://c.com"}, {"url": "https://d.com"}]`)
+
+	var results []BatchResult
+	json.NewDecoder(rr.Body).Decode(&results)
+	if len(results) != 3 {
+		t.Fatalf("Expected 2 results and a size error, got %d", len(results))
+	}
+	if last := results[2]; last.Status != http.StatusRequestEntityTooLarge || last.Index != 2 {
+		t.Errorf("Expected 413 for the first item over the limit, got %+v", last)
+	}
+	if storage.Count() != 2 {
+		t.Errorf("Expected only 2 links stored, got %d", storage.Count())
+	}
+}
+
+func TestHandler_ShortenBatch_InvalidBody(t *testing.T) {
+	handler := NewHandler(service.NewShortener(service.NewStorage()))
+
+	if rr := shortenBatch(handler, "   "); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for empty body, got %d", rr.Code)
+	}
+
+	// Un error de sintaxis en el array corta el lote con un resultado de error
+	rr := shortenBatch(handler, `[{"url": "https://a.com"}, {"url": `)
+	var results []BatchResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("Expected well-formed output, got error %v", err)
+	}
+	if len(results) != 2 || results[1].Status != http.StatusBadRequest {
+		t.Errorf("Expected a success and a syntax error, got %+v", results)
+	}
+}
+
+func TestHandler_ShortenBatch_RequestID(t *testing.T) {
+	handler := NewHandler(service.NewShortener(service.NewStorage()))
+	server := logging.AccessLog(slog.New(slog.NewTextHandler(io.Discard, nil)), http.HandlerFunc(handler.ShortenBatch))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten/batch", strings.NewReader(`[{"url": "https://a.com"}, {"url": "not a url"}]`))
+	req.Header.Set(logging.RequestIDHeader, "req-123")
+	rr := httptest.NewRecorder()
+	server.ServeHTTP(rr, req)
+
+	var results []BatchResult
+	if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+		t.Fatalf("Expected well-formed output, got error %v", err)
+	}
+	if len(results) != 2 || results[1].ErrorResponse == nil {
+		t.Fatalf("Expected a success and an error, got %+v", results)
+	}
+	if results[1].RequestID != "req-123" {
+		t.Errorf("Expected request id in item error, got %q", results[1].RequestID)
+	}
+}
+
+func TestHandler_ShortenBatch_StreamsOverHTTP(t *testing.T) {
+	handler := NewHandler(service.NewShortener(service.NewStorage()))
+	server := httptest.NewServer(http.HandlerFunc(handler.ShortenBatch))
+	defer server.Close()
+
+	// Bastantes elementos para que la respuesta empiece antes de leer todo el cuerpo
+	var body strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&body, `{"url": "https://www.example.com/%d"}`+"\n", i)
+	}
+	resp, err := http.Post(server.URL, "application/x-ndjson", strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatalf("Error posting batch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	created := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("Error decoding result: %v", err)
+		}
+		if result.Status != http.StatusCreated {
+			t.Fatalf("Expected item %d to be created, got %d %+v", result.Index, result.Status, result.ErrorResponse)
+		}
+		created++
+	}
+	if created != 1000 {
+		t.Errorf("Expected 1000 results, got %d", created)
+	}
+}
+
+func TestHandler_ShortenBatch_SizeLimits(t *testing.T) {
+	handler := NewHandler(service.NewShortener(service.NewStorage()), WithMaxBatchSize(2))
+
+	// Una línea NDJSON mayor que el límite corta el lote con un 413
+	long := `{"url": "https://a.com/` + strings.Repeat("x", maxBatchItemBytes) + `"}`
+	rr := shortenBatch(handler, `{"url": "https://a.com"}`+"\n"+long+"\n")
+	dec := json.NewDecoder(rr.Body)
+	var results []BatchResult
+	for dec.More() {
+		var result BatchResult
+		dec.Decode(&result)
+		results = append(results, result)
+	}
+	if len(results) != 2 || results[1].Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a success and a 413 for the long line, got %+v", results)
+	}
+
+	// El cuerpo entero se limita aunque no tenga elementos de más
+	padded := `[{"url": "https://b.com"}` + strings.Repeat(" ", 2*maxBatchItemBytes) + `, {"url": "https://c.com"}]`
+	results = nil
+	json.NewDecoder(shortenBatch(handler, padded).Body).Decode(&results)
+	if len(results) != 2 || results[1].Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a success and a 413 for the oversized body, got %+v", results)
+	}
+}
+
+func TestHandler_ShortenBatch_ExtendsTimeouts(t *testing.T) {
+	const timeout = 300 * time.Millisecond
+	handler := NewHandler(service.NewShortener(service.NewStorage()), WithBatchTimeouts(timeout, timeout))
+	server := httptest.NewUnstartedServer(http.HandlerFunc(handler.ShortenBatch))
+	server.Config.ReadTimeout = timeout
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	defer server.Close()
+
+	// El cliente envía tandas que, juntas, tardan más que los timeouts
+	const chunks = 5
+	body, writer := io.Pipe()
+	go func() {
+		for chunk := 0; chunk < chunks; chunk++ {
+			for i := 0; i < batchFlushEvery; i++ {
+				fmt.Fprintf(writer, `{"url": "https://www.example.com/%d/%d"}`+"\n", chunk, i)
+			}
+			time.Sleep(timeout / 2)
+		}
+		writer.Close()
+	}()
+
+	resp, err := http.Post(server.URL, "application/x-ndjson", body)
+	if err != nil {
+		t.Fatalf("Error posting batch: %v", err)
+	}
+	defer resp.Body.Close()
+	created := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err == nil && result.Status == http.StatusCreated {
+			created++
+		}
+	}
+	if created != chunks*batchFlushEvery {
+		t.Errorf("Expected %d items created, got %d", chunks*batchFlushEvery, created)
+	}
+}
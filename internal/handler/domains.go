@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jackparradev/url-inteligente/internal/logging"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+// WithDomains añade dominios cortos además del de WithBaseURL. Cada dominio
+// se da por su URL base (p. ej. "https://acme.link") y tiene su propio espacio
+// de códigos. Las URLs que no se pueden interpretar se ignoran.
+func WithDomains(baseURLs ...string) HandlerOption {
+	return func(h *Handler) {
+		for _, baseURL := range baseURLs {
+			if host, ok := domainHost(baseURL); ok {
+				h.domains[host] = strings.TrimSuffix(baseURL, "/")
+			}
+		}
+	}
+}
+
+// domainHost devuelve el host con el que se identifica el dominio de baseURL:
+// en minúsculas y sin el puerto por defecto del esquema.
+func domainHost(baseURL string) (string, bool) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return "", false
+	}
+	host := canonicalHost(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		host = canonicalHost(u.Hostname())
+	}
+	return host, true
+}
+
+func canonicalHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// domainFor devuelve el dominio de los enlaces servidos bajo host ("" para el
+// dominio por defecto) y si host corresponde a un dominio configurado.
+func (h *Handler) domainFor(host string) (string, bool) {
+	host = canonicalHost(host)
+	for _, candidate := range []string{host, stripPort(host)} {
+		if candidate == h.defaultHost {
+			return "", true
+		}
+		if _, ok := h.domains[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func stripPort(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return hostname
+	}
+	return host
+}
+
+// requestDomain devuelve el dominio al que va dirigida la petición según su
+// cabecera Host. Un host desconocido (p. ej. una IP) usa el dominio por defecto.
+func (h *Handler) requestDomain(r *http.Request) string {
+	domain, _ := h.domainFor(r.Host)
+	return domain
+}
+
+// shortenDomain decide el dominio de un enlace nuevo: el campo domain de la
+// petición si viene, o el de la cabecera Host. Devuelve false si el dominio
+// pedido no está configurado.
+func (h *Handler) shortenDomain(hostDomain, requested string) (string, bool) {
+	if requested == "" {
+		return hostDomain, true
+	}
+	return h.domainFor(requested)
+}
+
+// linkKey devuelve la clave del enlace {code} de la petición. El dominio sale
+// de ?domain= o, si no viene, de la cabecera Host. Si ?domain= no está
+// configurado o el código no es válido (p. ej. "%2F" en la ruta), responde
+// con el error y devuelve false.
+func (h *Handler) linkKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	domain := h.requestDomain(r)
+	if requested := r.URL.Query().Get("domain"); requested != "" {
+		var ok bool
+		if domain, ok = h.domainFor(requested); !ok {
+			respondWithUnknownDomain(w)
+			return "", false
+		}
+	}
+	code := r.PathValue("code")
+	if !service.ValidShortCode(code) {
+		respondWithError(w, "Short URL not found", http.StatusNotFound)
+		return "", false
+	}
+	key := service.LinkKey(domain, code)
+	logging.SetShortCode(r.Context(), key)
+	return key, true
+}
+
+// baseURLFor devuelve la URL base de un dominio.
+func (h *Handler) baseURLFor(domain string) string {
+	if baseURL, ok := h.domains[domain]; ok {
+		return baseURL
+	}
+	return h.baseURL
+}
+
+func respondWithUnknownDomain(w http.ResponseWriter) {
+	respondWithError(w, "Unknown domain", http.StatusBadRequest)
+}
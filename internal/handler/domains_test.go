@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+func newDomainsHandler() (*Handler, *http.ServeMux) {
+	h := NewHandler(service.NewShortener(service.NewStorage()),
+		WithBaseURL("https://sho.rt"),
+		WithDomains("https://acme.link/", "http://other.co:8080"))
+	mux := newLinksMux(h)
+	mux.HandleFunc("/shorten", h.ShortenURL)
+	return h, mux
+}
+
+func TestHandler_Domains_SameCodeDifferentTargets(t *testing.T) {
+	_, mux := newDomainsHandler()
+
+	tests := []struct {
+		host, domain, target, wantShortURL string
+	}{
+		{"sho.rt", "", "https://default.com", "https://sho.rt/promo"},
+		{"sho.rt", "acme.link", "https://acme.com", "https://acme.link/promo"},
+		{"OTHER.CO:8080", "", "https://other.com", "http://other.co:8080/promo"},
+	}
+	for _, tt := range tests {
+		rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: tt.target, Alias: "promo", Domain: tt.domain}, withHost(tt.host))
+		var response ShortenResponse
+		json.NewDecoder(rr.Body).Decode(&response)
+		if rr.Code != http.StatusCreated || response.ShortURL != tt.wantShortURL {
+			t.Errorf("Expected 201 with %s, got %d %+v", tt.wantShortURL, rr.Code, response)
+		}
+	}
+
+	redirects := map[string]string{
+		"sho.rt":        "https://default.com",
+		"acme.link":     "https://acme.com",
+		"acme.link:443": "https://acme.com",
+		"other.co:8080": "https://other.com",
+		// Un host desconocido (p. ej. una IP) usa el dominio por defecto
+		"10.0.0.1:8080": "https://default.com",
+	}
+	for host, want := range redirects {
+		rr := serve(mux, http.MethodGet, "/promo", nil, withHost(host))
+		if location := rr.Header().Get("Location"); rr.Code != http.StatusFound || location != want {
+			t.Errorf("Expected %s on %s to redirect to %s, got %d %s", "/promo", host, want, rr.Code, location)
+		}
+	}
+}
+
+// Una "/" en el código no debe dar acceso al espacio de nombres de otro
+// dominio: "acme.link/promo" en el dominio por defecto sería la clave del
+// enlace promo de acme.link.
+func TestHandler_Domains_NoCrossDomainKeys(t *testing.T) {
+	_, mux := newDomainsHandler()
+
+	rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://acme.com", Alias: "promo", Domain: "acme.link"}, withHost("sho.rt"))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", rr.Code)
+	}
+
+	rr = serve(mux, http.MethodGet, "/acme.link/promo", nil, withHost("sho.rt"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a code containing '/', got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		rr = serve(mux, method, "/api/v1/links/acme.link%2Fpromo", nil, withHost("sho.rt"))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s with an escaped '/', got %d", method, rr.Code)
+		}
+	}
+	rr = serve(mux, http.MethodPatch, "/api/v1/links/acme.link%2Fpromo", UpdateLinkRequest{URL: "https://evil.com"}, withHost("sho.rt"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for PATCH with an escaped '/', got %d", rr.Code)
+	}
+
+	// El enlace de acme.link sigue intacto
+	rr = serve(mux, http.MethodGet, "/promo", nil, withHost("acme.link"))
+	if location := rr.Header().Get("Location"); rr.Code != http.StatusFound || location != "https://acme.com" {
+		t.Errorf("Expected acme.link/promo to still redirect to https://acme.com, got %d %s", rr.Code, location)
+	}
+}
+
+func TestHandler_Domains_UnknownDomain(t *testing.T) {
+	_, mux := newDomainsHandler()
+
+	rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.google.com", Domain: "evil.com"}, withHost("sho.rt"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown domain, got %d", rr.Code)
+	}
+	rr = serve(mux, http.MethodGet, "/api/v1/links/promo?domain=evil.com", nil, withHost("sho.rt"))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown ?domain=, got %d", rr.Code)
+	}
+}
+
+func TestHandler_Domains_ManagementAPI(t *testing.T) {
+	_, mux := newDomainsHandler()
+	serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://default.com", Alias: "promo"}, withHost("sho.rt"))
+	serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://acme.com", Alias: "promo"}, withHost("acme.link"))
+
+	// ?domain= elige el dominio aunque la petición llegue por otro host
+	rr := serve(mux, http.MethodGet, "/api/v1/links/promo?domain=acme.link", nil, withHost("sho.rt"))
+	var link LinkResponse
+	json.NewDecoder(rr.Body).Decode(&link)
+	if link.Domain != "acme.link" || link.LongURL != "https://acme.com" || link.ShortURL != "https://acme.link/promo" {
+		t.Errorf("Expected acme.link link, got %+v", link)
+	}
+
+	rr = serve(mux, http.MethodDelete, "/api/v1/links/promo", nil, withHost("acme.link"))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rr.Code)
+	}
+
+	rr = serve(mux, http.MethodGet, "/api/v1/links", nil, withHost("sho.rt"))
+	var list LinkListResponse
+	json.NewDecoder(rr.Body).Decode(&list)
+	if len(list.Links) != 1 || list.Links[0].Domain != "" || list.Links[0].LongURL != "https://default.com" {
+		t.Errorf("Expected only the default domain link to remain, got %+v", list.Links)
+	}
+}
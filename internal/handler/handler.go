@@ -2,31 +2,187 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/jackparradev/url-inteligente/internal/analytics"
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/logging"
+	"github.com/jackparradev/url-inteligente/internal/metrics"
+	"github.com/jackparradev/url-inteligente/internal/ratelimit"
 	"github.com/jackparradev/url-inteligente/internal/service"
+	"github.com/jackparradev/url-inteligente/internal/validation"
+)
+
+const (
+	// DefaultBaseURL es la URL base por defecto de los enlaces cortos.
+	DefaultBaseURL = "http://localhost:8080"
+	// DefaultRedirectType es el código de redirección por defecto. 302 no se
+	// cachea, así que cambiar el destino de un enlace llega a todos los visitantes.
+	DefaultRedirectType = http.StatusFound
+	// DefaultRedirectCacheMaxAge es el max-age por defecto de las redirecciones permanentes.
+	DefaultRedirectCacheMaxAge = 24 * time.Hour
 )
 
 type Handler struct {
 	shortener *service.Shortener
+	// baseURL es la URL pública del dominio por defecto (sin barra final).
+	baseURL string
+	// defaultHost es el host de baseURL; sus enlaces tienen Domain vacío.
+	defaultHost string
+	// domains son los dominios adicionales: host -> URL base.
+	domains map[string]string
+	// auth es opcional; sin él no se exigen claves de API.
+	auth *auth.Store
+	// clicks y stats son opcionales; sin ellos no se registran clics.
+	clicks *analytics.Pipeline
+	stats  *analytics.Aggregator
+	// maxBatchSize es el máximo de elementos por petición de lote.
+	maxBatchSize int
+	// batchReadTimeout y batchWriteTimeout son los plazos de cada tanda de un
+	// lote; cero no los amplía (ver WithBatchTimeouts).
+	batchReadTimeout  time.Duration
+	batchWriteTimeout time.Duration
+	// defaultRedirect es el código de redirección de los enlaces sin tipo propio.
+	defaultRedirect int
+	// redirectMaxAge es el tiempo que se permite cachear una redirección permanente.
+	redirectMaxAge time.Duration
+	// createLimit y redirectLimit son opcionales; sin ellos no se limita el ritmo.
+	createLimit   *ratelimit.Limiter
+	redirectLimit *ratelimit.Limiter
+	// batchLimit, opcional, limita los elementos de los lotes.
+	batchLimit *ratelimit.Limiter
+	// redirects es opcional; cuenta las redirecciones por resultado.
+	redirects *metrics.Counter
+	// readiness son las comprobaciones de /readyz; sin ellas siempre está listo.
+	readiness []readinessCheck
+}
+
+// HandlerOption configura opciones opcionales del Handler.
+type HandlerOption func(*Handler)
+
+// WithMetrics registra en reg el contador de redirecciones por resultado
+// (hit, miss, expired o blocked).
+func WithMetrics(reg *metrics.Registry) HandlerOption {
+	return func(h *Handler) {
+		h.redirects = reg.NewCounter("urli_redirects_total", "Short link resolutions by result.", "result")
+	}
+}
+
+// WithAnalytics registra cada redirección en clicks y expone las
+// estadísticas agregadas de stats.
+func WithAnalytics(clicks *analytics.Pipeline, stats *analytics.Aggregator) HandlerOption {
+	return func(h *Handler) {
+		h.clicks = clicks
+		h.stats = stats
+	}
 }
 
 type ShortenRequest struct {
 	URL string `json:"url"`
+	// ExpiresAt es el instante (RFC 3339) en que expira el enlace. Opcional.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTLSeconds es la vida del enlace en segundos. Opcional, excluyente con ExpiresAt.
+	TTLSeconds int64 `json:"ttl_seconds,omitempty"`
+	// Alias es un código corto personalizado (p. ej. "spring-sale"). Opcional.
+	Alias string `json:"alias,omitempty"`
+	// RedirectType es el código de la redirección (301, 302, 307 o 308).
+	// Opcional; por defecto se usa el del servidor.
+	RedirectType int `json:"redirect_type,omitempty"`
+	// Domain es el dominio corto del enlace (p. ej. "acme.link"). Opcional;
+	// por defecto el de la cabecera Host.
+	Domain string `json:"domain,omitempty"`
 }
 
 type ShortenResponse struct {
-	ShortURL string `json:"short_url"`
-	LongURL  string `json:"long_url"`
+	ShortURL  string     `json:"short_url"`
+	LongURL   string     `json:"long_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// RedirectType es el código con el que redirige el enlace.
+	RedirectType int `json:"redirect_type"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	// Reason es el motivo de rechazo de la URL (p. ej. "bad_scheme"), si aplica.
+	Reason string `json:"reason,omitempty"`
+	// RequestID es el identificador de la petición, para buscarla en los logs.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// maxTTLSeconds es el mayor ttl_seconds, en valor absoluto, que cabe en un
+// time.Duration; por encima la conversión desbordaría y daría un TTL
+// arbitrario.
+const maxTTLSeconds = math.MaxInt64 / int64(time.Second)
+
+// createOptions traduce los campos opcionales de la petición.
+func (req ShortenRequest) createOptions() (service.CreateOptions, error) {
+	if req.TTLSeconds > maxTTLSeconds || req.TTLSeconds < -maxTTLSeconds {
+		return service.CreateOptions{}, fmt.Errorf("%w: ttl_seconds must be between %d and %d",
+			service.ErrInvalidExpiry, -maxTTLSeconds, maxTTLSeconds)
+	}
+	opts := service.CreateOptions{
+		TTL:          time.Duration(req.TTLSeconds) * time.Second,
+		Alias:        req.Alias,
+		RedirectType: req.RedirectType,
+	}
+	if req.ExpiresAt != nil {
+		opts.ExpiresAt = *req.ExpiresAt
+	}
+	return opts, nil
+}
+
+// WithMaxBatchSize cambia el máximo de elementos por petición de lote.
+func WithMaxBatchSize(n int) HandlerOption {
+	return func(h *Handler) {
+		h.maxBatchSize = n
+	}
+}
+
+// WithBaseURL cambia la URL pública con la que se construyen los enlaces cortos.
+func WithBaseURL(baseURL string) HandlerOption {
+	return func(h *Handler) {
+		h.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
 }
 
-func NewHandler(shortener *service.Shortener) *Handler {
-	return &Handler{shortener: shortener}
+// WithDefaultRedirectType cambia el código de redirección de los enlaces que
+// no eligieron uno. Debe ser 301, 302, 307 o 308.
+func WithDefaultRedirectType(code int) HandlerOption {
+	return func(h *Handler) {
+		h.defaultRedirect = code
+	}
+}
+
+// WithRedirectCacheMaxAge cambia el max-age de las redirecciones permanentes.
+func WithRedirectCacheMaxAge(maxAge time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.redirectMaxAge = maxAge
+	}
+}
+
+func NewHandler(shortener *service.Shortener, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		shortener:       shortener,
+		baseURL:         DefaultBaseURL,
+		domains:         make(map[string]string),
+		maxBatchSize:    DefaultMaxBatchSize,
+		defaultRedirect: DefaultRedirectType,
+		redirectMaxAge:  DefaultRedirectCacheMaxAge,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	// El dominio por defecto no se guarda como dominio adicional
+	h.defaultHost, _ = domainHost(h.baseURL)
+	delete(h.domains, h.defaultHost)
+	return h
 }
 
 func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
@@ -41,29 +197,35 @@ func (h *Handler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validación básica de URL
-	if !isValidURL(req.URL) {
-		respondWithError(w, "Invalid URL format", http.StatusBadRequest)
+	domain, ok := h.shortenDomain(h.requestDomain(r), req.Domain)
+	if !ok {
+		respondWithUnknownDomain(w)
 		return
 	}
-
-	// Generar código corto
-	shortCode, err := h.shortener.CreateShortURL(req.URL)
+	opts, err := req.createOptions()
 	if err != nil {
-		respondWithError(w, "Error creating short URL", http.StatusInternalServerError)
+		respondWithServiceError(w, err, "Error creating short URL")
 		return
 	}
+	opts.Domain = domain
+	opts.Owner = h.owner(r)
 
-	// Base URL hardcodeada según restricciones
-	baseURL := "http://localhost:8080/"
-	shortURL := baseURL + shortCode
+	// Validar, normalizar y generar código corto
+	link, created, err := h.shortener.CreateContext(r.Context(), req.URL, opts)
+	if err != nil {
+		respondWithServiceError(w, err, "Error creating short URL")
+		return
+	}
+	response := h.shortenResponse(link)
 
-	response := ShortenResponse{
-		ShortURL: shortURL,
-		LongURL:  req.URL,
+	// 201 si el enlace es nuevo, 200 si la deduplicación reutilizó uno existente
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -82,42 +244,192 @@ func (h *Handler) RedirectURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Buscar URL larga
-	longURL, exists := h.shortener.GetLongURL(shortCode)
-	if !exists {
+	// Una "/" en el código apuntaría al espacio de nombres de otro dominio
+	if !service.ValidShortCode(shortCode) {
+		h.countRedirect("miss")
 		respondWithError(w, "Short URL not found", http.StatusNotFound)
 		return
 	}
 
-	// Redireccionar (301 - Moved Permanently)
-	// Justificación: Para URLs acortadas, 301 es apropiado porque
-	// el mapeo es permanente y permite caching del navegador
-	http.Redirect(w, r, longURL, http.StatusMovedPermanently)
+	// Buscar URL larga en el dominio de la petición
+	key := service.LinkKey(h.requestDomain(r), shortCode)
+	logging.SetShortCode(r.Context(), key)
+	link, err := h.shortener.ResolveContext(r.Context(), key)
+	var blocked *service.BlockedError
+	if errors.As(err, &blocked) {
+		h.countRedirect("blocked")
+		respondWithWarningPage(w, blocked.Link)
+		return
+	}
+	if errors.Is(err, service.ErrExpired) {
+		h.countRedirect("expired")
+		respondWithError(w, "Short URL has expired", http.StatusGone)
+		return
+	}
+	if err != nil {
+		h.countRedirect("miss")
+		respondWithError(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+
+	h.countRedirect("hit")
+	h.recordClick(r, key)
+
+	// Las redirecciones permanentes se cachean un tiempo acotado; las
+	// temporales no se cachean para que un cambio de destino llegue a todos
+	status := h.redirectType(link)
+	if status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.redirectMaxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	http.Redirect(w, r, link.LongURL, status)
 }
 
-// Función auxiliar para responder con errores
-func respondWithError(w http.ResponseWriter, message string, code int) {
+// LinkStats responde con las estadísticas de clics de un código
+// (GET /api/links/{code}/stats).
+func (h *Handler) LinkStats(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		respondWithError(w, "Analytics are disabled", http.StatusNotFound)
+		return
+	}
+
+	key, ok := h.linkKey(w, r)
+	if !ok {
+		return
+	}
+	if link, exists := h.shortener.Lookup(key); !exists || !h.canAccess(r, link) {
+		respondWithError(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+
+	// Las estadísticas se agregan por clave; al cliente se le muestra el código
+	stats := h.stats.Stats(key)
+	stats.Code = r.PathValue("code")
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: message})
+	json.NewEncoder(w).Encode(stats)
 }
 
-// Validación básica de URL (solo stdlib)
-func isValidURL(url string) bool {
-	// Validación básica sin librerías externas
-	if len(url) < 8 {
-		return false
+func (h *Handler) countRedirect(result string) {
+	if h.redirects != nil {
+		h.redirects.Inc(result)
 	}
+}
 
-	// Debe empezar con http:// o https://
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		return false
+// recordClick encola el clic del enlace con la clave key sin bloquear la redirección.
+func (h *Handler) recordClick(r *http.Request, key string) {
+	if h.clicks == nil {
+		return
 	}
+	h.clicks.Record(analytics.Click{
+		Code:      key,
+		Time:      time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IPHash:    h.clicks.HashIP(clientIP(r)),
+	})
+}
 
-	// Debe tener al menos un punto después del protocolo
-	if !strings.Contains(url[8:], ".") {
-		return false
+// clientIP devuelve la IP del cliente de la conexión.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
+	return host
+}
+
+// shortURL construye la URL corta pública de un enlace con la URL base de su dominio.
+func (h *Handler) shortURL(link service.Link) string {
+	return h.baseURLFor(link.Domain) + "/" + link.ShortCode
+}
+
+// shortenResponse construye la respuesta de creación de un enlace.
+func (h *Handler) shortenResponse(link service.Link) ShortenResponse {
+	response := ShortenResponse{
+		ShortURL: h.shortURL(link),
+		// Se devuelve la forma canónica que realmente se guardó
+		LongURL: link.LongURL,
+	}
+	if !link.ExpiresAt.IsZero() {
+		response.ExpiresAt = &link.ExpiresAt
+	}
+	response.RedirectType = h.redirectType(link)
+	return response
+}
+
+// redirectType devuelve el código con el que redirige link.
+func (h *Handler) redirectType(link service.Link) int {
+	if link.RedirectType != 0 {
+		return link.RedirectType
+	}
+	return h.defaultRedirect
+}
+
+// serviceError traduce un error del servicio a su respuesta y código HTTP.
+// fallback es el mensaje para errores internos, que no se exponen al cliente.
+func serviceError(err error, fallback string) (ErrorResponse, int) {
+	var invalid *validation.Error
+	switch {
+	case errors.As(err, &invalid):
+		return ErrorResponse{Error: err.Error(), Reason: string(invalid.Reason)}, http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidURL),
+		errors.Is(err, service.ErrInvalidExpiry),
+		errors.Is(err, service.ErrInvalidRedirectType),
+		errors.Is(err, service.ErrInvalidAlias),
+		errors.Is(err, service.ErrAliasReserved):
+		return ErrorResponse{Error: err.Error()}, http.StatusBadRequest
+	case errors.Is(err, service.ErrBlocked):
+		return ErrorResponse{Error: err.Error(), Reason: "blocked"}, http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrAliasTaken):
+		return ErrorResponse{Error: err.Error()}, http.StatusConflict
+	// Los enlaces ajenos se tratan como inexistentes (ver canAccess)
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrNotOwner):
+		return ErrorResponse{Error: "Short URL not found"}, http.StatusNotFound
+	case errors.Is(err, service.ErrStorageFull):
+		return ErrorResponse{Error: "Storage is full"}, http.StatusInsufficientStorage
+	default:
+		return ErrorResponse{Error: fallback}, http.StatusInternalServerError
+	}
+}
+
+func respondWithServiceError(w http.ResponseWriter, err error, fallback string) {
+	response, code := serviceError(err, fallback)
+	respondWithErrorResponse(w, response, code)
+}
+
+// Función auxiliar para responder con errores
+func respondWithError(w http.ResponseWriter, message string, code int) {
+	respondWithErrorResponse(w, ErrorResponse{Error: message}, code)
+}
+
+func respondWithErrorResponse(w http.ResponseWriter, response ErrorResponse, code int) {
+	// El log de acceso deja el identificador en la cabecera antes de llamar al handler
+	response.RequestID = w.Header().Get(logging.RequestIDHeader)
+	respondWithJSON(w, response, code)
+}
+
+// warningPage se muestra en lugar de redirigir cuando el destino está
+// bloqueado. El destino se muestra como texto, sin enlace.
+var warningPage = template.Must(template.New("warning").Parse(`<!DOCTYPE html>
+<html lang="es">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Enlace bloqueado</title>
+</head>
+<body>
+<h1>Este enlace ha sido bloqueado</h1>
+<p>El destino de este enlace corto figura en nuestra lista de sitios maliciosos o no permitidos, por lo que no se redirige automáticamente.</p>
+<p>Destino: <code>{{.LongURL}}</code></p>
+</body>
+</html>
+`))
 
-	return true
+// respondWithWarningPage responde con la página de aviso para un destino bloqueado.
+func respondWithWarningPage(w http.ResponseWriter, link service.Link) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+	warningPage.Execute(w, link)
 }
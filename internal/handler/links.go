@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+const (
+	// defaultPageSize y maxPageSize acotan el tamaño de página del listado.
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// LinkResponse son los metadatos de un enlace en la API de gestión.
+type LinkResponse struct {
+	Code string `json:"code"`
+	// Domain es el dominio corto del enlace; vacío en el dominio por defecto.
+	Domain string `json:"domain,omitempty"`
+	// Owner es el propietario de la clave de API que creó el enlace.
+	Owner     string     `json:"owner,omitempty"`
+	ShortURL  string     `json:"short_url"`
+	LongURL   string     `json:"long_url"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// RedirectType es el código con el que redirige el enlace.
+	RedirectType int `json:"redirect_type"`
+	// Clicks es el total de clics registrados (0 si la analítica está desactivada).
+	Clicks uint64 `json:"clicks"`
+}
+
+// LinkListResponse es una página del listado de enlaces.
+type LinkListResponse struct {
+	Links []LinkResponse `json:"links"`
+	// NextCursor se pasa como ?cursor= para pedir la página siguiente. Vacío
+	// en la última página.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// UpdateLinkRequest son los campos modificables de un enlace. Los campos
+// ausentes no se modifican.
+type UpdateLinkRequest struct {
+	URL string `json:"url,omitempty"`
+	// RedirectType es el nuevo código de redirección (0 = el del servidor).
+	RedirectType *int `json:"redirect_type,omitempty"`
+}
+
+// ListLinks lista los enlaces de todos los dominios, ordenados por dominio y
+// código (GET /api/v1/links). Con autenticación, solo los del propietario de
+// la clave salvo con el permiso admin.
+// Acepta ?limit= (por defecto 50, máximo 500) y ?cursor=.
+func (h *Handler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	limit := defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			respondWithError(w, "limit must be between 1 and "+strconv.Itoa(maxPageSize), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	after, err := decodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		respondWithError(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	// Pedir uno más permite saber si hay página siguiente
+	var links []service.Link
+	if h.seesAllLinks(r) {
+		links = h.shortener.List(after, limit+1)
+	} else {
+		links = h.shortener.ListOwned(h.owner(r), after, limit+1)
+	}
+	response := LinkListResponse{Links: make([]LinkResponse, 0, min(len(links), limit))}
+	if len(links) > limit {
+		links = links[:limit]
+		response.NextCursor = encodeCursor(links[limit-1].Key())
+	}
+	for _, link := range links {
+		response.Links = append(response.Links, h.linkResponse(link))
+	}
+
+	respondWithJSON(w, response, http.StatusOK)
+}
+
+// GetLink devuelve los metadatos de un enlace (GET /api/v1/links/{code}).
+// El dominio sale de ?domain= o de la cabecera Host, igual que en el resto
+// de la API de gestión.
+func (h *Handler) GetLink(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.linkKey(w, r)
+	if !ok {
+		return
+	}
+	link, exists := h.shortener.Lookup(key)
+	if !exists || !h.canAccess(r, link) {
+		respondWithError(w, "Short URL not found", http.StatusNotFound)
+		return
+	}
+	respondWithJSON(w, h.linkResponse(link), http.StatusOK)
+}
+
+// UpdateLink cambia el destino o el tipo de redirección de un enlace
+// (PATCH /api/v1/links/{code}).
+func (h *Handler) UpdateLink(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.linkKey(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" && req.RedirectType == nil {
+		respondWithError(w, "url or redirect_type is required", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.updateLink(r, key, service.LinkUpdate{
+		LongURL:      req.URL,
+		RedirectType: req.RedirectType,
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "Error updating short URL")
+		return
+	}
+	respondWithJSON(w, h.linkResponse(link), http.StatusOK)
+}
+
+// DeleteLink elimina un enlace (DELETE /api/v1/links/{code}).
+func (h *Handler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.linkKey(w, r)
+	if !ok {
+		return
+	}
+	if err := h.deleteLink(r, key); err != nil {
+		respondWithServiceError(w, err, "Error deleting short URL")
+		return
+	}
+	// Un alias reutilizado más adelante no debe heredar los clics
+	if h.stats != nil {
+		h.stats.Forget(key)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) linkResponse(link service.Link) LinkResponse {
+	response := LinkResponse{
+		Code:     link.ShortCode,
+		Domain:   link.Domain,
+		Owner:    link.Owner,
+		ShortURL: h.shortURL(link),
+		LongURL:  link.LongURL,
+		// Se expone el código efectivo, incluido el valor por defecto
+		RedirectType: h.redirectType(link),
+	}
+	if !link.CreatedAt.IsZero() {
+		response.CreatedAt = &link.CreatedAt
+	}
+	if !link.ExpiresAt.IsZero() {
+		response.ExpiresAt = &link.ExpiresAt
+	}
+	if h.stats != nil {
+		response.Clicks = h.stats.TotalClicks(link.Key())
+	}
+	return response
+}
+
+// updateLink aplica update al enlace key si la petición puede modificarlo.
+// El propietario se comprueba en la misma operación que la escritura, para
+// no modificar un enlace recreado por otro entre la comprobación y el cambio.
+func (h *Handler) updateLink(r *http.Request, key string, update service.LinkUpdate) (service.Link, error) {
+	if h.seesAllLinks(r) {
+		return h.shortener.Update(key, update)
+	}
+	return h.shortener.UpdateOwned(h.owner(r), key, update)
+}
+
+// deleteLink borra el enlace key si la petición puede modificarlo, igual que updateLink.
+func (h *Handler) deleteLink(r *http.Request, key string) error {
+	if h.seesAllLinks(r) {
+		return h.shortener.Delete(key)
+	}
+	return h.shortener.DeleteOwned(h.owner(r), key)
+}
+
+// encodeCursor y decodeCursor ocultan que el cursor es la clave del último
+// enlace devuelto, para poder cambiar su formato sin romper a los clientes.
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	after, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(after), err
+}
+
+func respondWithJSON(w http.ResponseWriter, response any, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/analytics"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+// newLinksMux registra las rutas de la API de gestión como en main.
+func newLinksMux(h *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/links", h.ListLinks)
+	mux.HandleFunc("GET /api/v1/links/{code}", h.GetLink)
+	mux.HandleFunc("PATCH /api/v1/links/{code}", h.UpdateLink)
+	mux.HandleFunc("DELETE /api/v1/links/{code}", h.DeleteLink)
+	mux.HandleFunc("/", h.RedirectURL)
+	return mux
+}
+
+func serve(mux http.Handler, method, target string, body any, opts ...func(*http.Request)) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	for _, opt := range opts {
+		opt(req)
+	}
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+// withHost hace que serve envíe la petición con la cabecera Host host.
+func withHost(host string) func(*http.Request) {
+	return func(req *http.Request) { req.Host = host }
+}
+
+func TestHandler_ListLinks_Pagination(t *testing.T) {
+	storage := service.NewStorage()
+	for i := 0; i < 5; i++ {
+		storage.Store(service.Link{ShortCode: fmt.Sprintf("code%d", i), LongURL: fmt.Sprintf("https://test%d.com", i)})
+	}
+	mux := newLinksMux(NewHandler(service.NewShortener(storage)))
+
+	var codes []string
+	target := "/api/v1/links?limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Expected pagination to terminate")
+		}
+		rr := serve(mux, http.MethodGet, target, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rr.Code)
+		}
+		var page LinkListResponse
+		json.NewDecoder(rr.Body).Decode(&page)
+		for _, link := range page.Links {
+			codes = append(codes, link.Code)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		target = "/api/v1/links?limit=2&cursor=" + page.NextCursor
+	}
+
+	if fmt.Sprint(codes) != "[code0 code1 code2 code3 code4]" {
+		t.Errorf("Expected all codes in order, got %v", codes)
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=501", "?limit=abc", "?cursor=***"} {
+		if rr := serve(mux, http.MethodGet, "/api/v1/links"+query, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestHandler_ListLinks_Empty(t *testing.T) {
+	mux := newLinksMux(NewHandler(service.NewShortener(service.NewStorage())))
+
+	rr := serve(mux, http.MethodGet, "/api/v1/links", nil)
+	if rr.Body.String() != "{\"links\":[]}\n" {
+		t.Errorf("Expected empty list, got %s", rr.Body.String())
+	}
+}
+
+func TestHandler_GetLink(t *testing.T) {
+	storage := service.NewStorage()
+	aggregator := analytics.NewAggregator()
+	aggregator.Consume(analytics.Click{Code: "abc123"})
+	mux := newLinksMux(NewHandler(service.NewShortener(storage), WithAnalytics(analytics.NewPipeline(analytics.Options{}), aggregator)))
+
+	code, _ := service.NewShortener(storage).CreateShortURL("https://www.google.com")
+	storage.Store(service.Link{ShortCode: "abc123", LongURL: "https://www.github.com"})
+
+	rr := serve(mux, http.MethodGet, "/api/v1/links/"+code, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var link LinkResponse
+	json.NewDecoder(rr.Body).Decode(&link)
+	if link.Code != code || link.LongURL != "https://www.google.com" || link.CreatedAt == nil {
+		t.Errorf("Unexpected link %+v", link)
+	}
+	if link.ShortURL != "http://localhost:8080/"+code {
+		t.Errorf("Unexpected short URL %s", link.ShortURL)
+	}
+
+	rr = serve(mux, http.MethodGet, "/api/v1/links/abc123", nil)
+	json.NewDecoder(rr.Body).Decode(&link)
+	if link.Clicks != 1 {
+		t.Errorf("Expected 1 click, got %d", link.Clicks)
+	}
+
+	if rr := serve(mux, http.MethodGet, "/api/v1/links/missing", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rr.Code)
+	}
+}
+
+func TestHandler_UpdateLink(t *testing.T) {
+	storage := service.NewStorage()
+	storage.Store(service.Link{ShortCode: "abc123", LongURL: "https://old.com"})
+	mux := newLinksMux(NewHandler(service.NewShortener(storage)))
+
+	rr := serve(mux, http.MethodPatch, "/api/v1/links/abc123", UpdateLinkRequest{URL: "https://New.com/"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var link LinkResponse
+	json.NewDecoder(rr.Body).Decode(&link)
+	if link.LongURL != "https://new.com" {
+		t.Errorf("Expected canonical target, got %s", link.LongURL)
+	}
+
+	rr = serve(mux, http.MethodGet, "/abc123", nil)
+	if location := rr.Header().Get("Location"); location != "https://new.com" {
+		t.Errorf("Expected redirect to new target, got %s", location)
+	}
+
+	tests := []struct {
+		code string
+		body any
+		want int
+	}{
+		{"abc123", UpdateLinkRequest{URL: "ftp://new.com"}, http.StatusBadRequest},
+		{"abc123", UpdateLinkRequest{}, http.StatusBadRequest},
+		{"abc123", "not an object", http.StatusBadRequest},
+		{"missing", UpdateLinkRequest{URL: "https://new.com"}, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		if rr := serve(mux, http.MethodPatch, "/api/v1/links/"+test.code, test.body); rr.Code != test.want {
+			t.Errorf("Expected status %d for %+v, got %d", test.want, test.body, rr.Code)
+		}
+	}
+}
+
+func TestHandler_DeleteLink(t *testing.T) {
+	storage := service.NewStorage()
+	storage.Store(service.Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	aggregator := analytics.NewAggregator()
+	aggregator.Consume(analytics.Click{Code: "abc123"})
+	mux := newLinksMux(NewHandler(service.NewShortener(storage), WithAnalytics(analytics.NewPipeline(analytics.Options{}), aggregator)))
+
+	if rr := serve(mux, http.MethodDelete, "/api/v1/links/abc123", nil); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rr.Code)
+	}
+	if storage.Exists("abc123") {
+		t.Error("Expected link to be deleted")
+	}
+	if aggregator.TotalClicks("abc123") != 0 {
+		t.Error("Expected stats to be forgotten")
+	}
+	if rr := serve(mux, http.MethodGet, "/abc123", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodDelete, "/api/v1/links/abc123", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 on second delete, got %d", rr.Code)
+	}
+}
+
+func TestHandler_RedirectType(t *testing.T) {
+	h := NewHandler(service.NewShortener(service.NewStorage()), WithRedirectCacheMaxAge(time.Hour))
+	mux := newLinksMux(h)
+	mux.HandleFunc("/shorten", h.ShortenURL)
+
+	tests := []struct {
+		redirectType int
+		wantStatus   int
+		wantCache    string
+	}{
+		{0, http.StatusFound, "no-store"},
+		{http.StatusMovedPermanently, http.StatusMovedPermanently, "public, max-age=3600"},
+		{http.StatusTemporaryRedirect, http.StatusTemporaryRedirect, "no-store"},
+		{http.StatusPermanentRedirect, http.StatusPermanentRedirect, "public, max-age=3600"},
+	}
+	for _, tt := range tests {
+		rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.google.com", RedirectType: tt.redirectType})
+		var response ShortenResponse
+		json.NewDecoder(rr.Body).Decode(&response)
+		if rr.Code != http.StatusCreated || response.RedirectType != tt.wantStatus {
+			t.Fatalf("Expected 201 with redirect_type %d, got %d %+v", tt.wantStatus, rr.Code, response)
+		}
+
+		rr = serve(mux, http.MethodGet, strings.TrimPrefix(response.ShortURL, "http://localhost:8080"), nil)
+		if rr.Code != tt.wantStatus {
+			t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+		}
+		if got := rr.Header().Get("Cache-Control"); got != tt.wantCache {
+			t.Errorf("Expected Cache-Control %q for %d, got %q", tt.wantCache, tt.wantStatus, got)
+		}
+	}
+
+	rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.google.com", RedirectType: 303})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for redirect_type 303, got %d", rr.Code)
+	}
+}
+
+func TestHandler_UpdateLink_RedirectType(t *testing.T) {
+	storage := service.NewStorage()
+	storage.Store(service.Link{ShortCode: "abc123", LongURL: "https://old.com"})
+	mux := newLinksMux(NewHandler(service.NewShortener(storage)))
+
+	rr := serve(mux, http.MethodPatch, "/api/v1/links/abc123", map[string]int{"redirect_type": 301})
+	var response LinkResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	if rr.Code != http.StatusOK || response.RedirectType != 301 || response.LongURL != "https://old.com" {
+		t.Errorf("Expected redirect type updated to 301, got %d %+v", rr.Code, response)
+	}
+
+	if rr := serve(mux, http.MethodPatch, "/api/v1/links/abc123", map[string]any{}); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty update, got %d", rr.Code)
+	}
+}
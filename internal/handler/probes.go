@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// readinessTimeout acota cuánto puede tardar /readyz en comprobar todo.
+const readinessTimeout = 2 * time.Second
+
+// readinessCheck es una condición que debe cumplirse para recibir tráfico.
+type readinessCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// WithReadinessCheck añade una comprobación a /readyz. Las comprobaciones se
+// ejecutan en el orden en que se registran.
+func WithReadinessCheck(name string, check func(context.Context) error) HandlerOption {
+	return func(h *Handler) {
+		h.readiness = append(h.readiness, readinessCheck{name: name, check: check})
+	}
+}
+
+// HealthResponse es la respuesta de /healthz y /readyz.
+type HealthResponse struct {
+	Status string `json:"status"`
+	// Checks tiene el resultado de cada comprobación de /readyz: "ok" o el error.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// VersionResponse es la información de compilación que devuelve /version.
+type VersionResponse struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	// Revision, Time y Modified vienen del control de versiones y solo están
+	// si el binario se compiló dentro del repositorio.
+	Revision string `json:"revision,omitempty"`
+	Time     string `json:"time,omitempty"`
+	Modified bool   `json:"modified,omitempty"`
+}
+
+// Healthz responde a la sonda de vida (GET /healthz): si el proceso puede
+// atender peticiones HTTP, está vivo. No comprueba dependencias, para que un
+// fallo del storage no haga que el orquestador reinicie el proceso.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, HealthResponse{Status: "ok"}, http.StatusOK)
+}
+
+// Readyz responde a la sonda de disponibilidad (GET /readyz) con 200 si todas
+// las comprobaciones registradas pasan y con 503 si alguna falla.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	response := HealthResponse{Status: "ready", Checks: make(map[string]string, len(h.readiness))}
+	code := http.StatusOK
+	for _, c := range h.readiness {
+		if err := c.check(ctx); err != nil {
+			response.Checks[c.name] = err.Error()
+			response.Status, code = "not ready", http.StatusServiceUnavailable
+			continue
+		}
+		response.Checks[c.name] = "ok"
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, response, code)
+}
+
+// Version devuelve la información de compilación del binario (GET /version).
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, buildInfo(), http.StatusOK)
+}
+
+// buildInfo lee una sola vez la información que el compilador incrusta en el
+// binario.
+var buildInfo = sync.OnceValue(func() VersionResponse {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return VersionResponse{Version: "unknown"}
+	}
+	response := VersionResponse{
+		Module:    info.Main.Path,
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			response.Revision = setting.Value
+		case "vcs.time":
+			response.Time = setting.Value
+		case "vcs.modified":
+			response.Modified = setting.Value == "true"
+		}
+	}
+	return response
+})
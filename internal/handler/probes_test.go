@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+func newProbeMux(opts ...HandlerOption) *http.ServeMux {
+	h := NewHandler(service.NewShortener(service.NewStorage()), opts...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", h.ShortenURL)
+	mux.HandleFunc("GET /healthz", h.Healthz)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+	mux.HandleFunc("GET /version", h.Version)
+	mux.HandleFunc("/", h.RedirectURL)
+	return mux
+}
+
+func TestHandler_Healthz(t *testing.T) {
+	failing := WithReadinessCheck("storage", func(context.Context) error { return errors.New("down") })
+	rr := serve(newProbeMux(failing), http.MethodGet, "/healthz", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected liveness to ignore readiness checks, got %d", rr.Code)
+	}
+	var response HealthResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	if response.Status != "ok" {
+		t.Errorf("Expected status ok, got %q", response.Status)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, got %q", got)
+	}
+}
+
+func TestHandler_Readyz(t *testing.T) {
+	var storageErr error
+	mux := newProbeMux(
+		WithReadinessCheck("storage", func(context.Context) error { return storageErr }),
+		WithReadinessCheck("shutdown", func(context.Context) error { return nil }),
+	)
+
+	rr := serve(mux, http.MethodGet, "/readyz", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var response HealthResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	if response.Status != "ready" || response.Checks["storage"] != "ok" || response.Checks["shutdown"] != "ok" {
+		t.Errorf("Unexpected readiness response %+v", response)
+	}
+
+	storageErr = service.ErrClosed
+	rr = serve(mux, http.MethodGet, "/readyz", nil)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", rr.Code)
+	}
+	response = HealthResponse{}
+	json.NewDecoder(rr.Body).Decode(&response)
+	if response.Status != "not ready" || response.Checks["storage"] != service.ErrClosed.Error() {
+		t.Errorf("Expected failing storage check, got %+v", response)
+	}
+	if response.Checks["shutdown"] != "ok" {
+		t.Errorf("Expected remaining checks to run, got %+v", response.Checks)
+	}
+}
+
+func TestHandler_Version(t *testing.T) {
+	rr := serve(newProbeMux(), http.MethodGet, "/version", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var response VersionResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	if response.GoVersion != runtime.Version() {
+		t.Errorf("Expected go version %q, got %q", runtime.Version(), response.GoVersion)
+	}
+}
+
+func TestHandler_ProbePathsReserved(t *testing.T) {
+	mux := newProbeMux()
+	for _, alias := range []string{"healthz", "readyz", "version"} {
+		rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com", Alias: alias})
+		var response ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&response)
+		if rr.Code != http.StatusBadRequest || !strings.HasPrefix(response.Error, service.ErrAliasReserved.Error()) {
+			t.Errorf("Expected alias %q to be reserved, got %d %q", alias, rr.Code, response.Error)
+		}
+	}
+}
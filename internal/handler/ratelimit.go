@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/ratelimit"
+)
+
+// WithRateLimits limita el ritmo de las rutas envueltas con LimitCreate y
+// LimitRedirect. Un limitador nil deja ese tipo de tráfico sin límite.
+func WithRateLimits(create, redirect *ratelimit.Limiter) HandlerOption {
+	return func(h *Handler) {
+		h.createLimit = create
+		h.redirectLimit = redirect
+	}
+}
+
+// WithBatchRateLimit limita los elementos de los lotes con limiter, aparte
+// del límite de creación: un lote entero solo paga una petición de este, así
+// que una importación grande no se queda sin tokens a los pocos elementos.
+// Un limitador nil deja los elementos sin límite.
+func WithBatchRateLimit(limiter *ratelimit.Limiter) HandlerOption {
+	return func(h *Handler) {
+		h.batchLimit = limiter
+	}
+}
+
+// LimitCreate envuelve next con el límite de creación de enlaces. Cada
+// petición consume un token, también las de lotes; sus elementos se limitan
+// con WithBatchRateLimit. Debe ir dentro de Require para limitar por clave.
+func (h *Handler) LimitCreate(next http.HandlerFunc) http.HandlerFunc {
+	return h.limit(h.createLimit, next)
+}
+
+// LimitRedirect envuelve next con el límite de redirecciones.
+func (h *Handler) LimitRedirect(next http.HandlerFunc) http.HandlerFunc {
+	return h.limit(h.redirectLimit, next)
+}
+
+func (h *Handler) limit(limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil || limiter.Limit().Unlimited() {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		decision := limiter.Allow(rateLimitKey(r))
+		setRateLimitHeaders(w, decision)
+		if !decision.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+			respondWithError(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitKey identifica al cliente: por su clave de API si la petición
+// está autenticada, de modo que varias IPs comparten su límite, o por su IP.
+func rateLimitKey(r *http.Request) string {
+	if key, ok := auth.FromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+	return "ip:" + clientIP(r)
+}
+
+// setRateLimitHeaders añade las cabeceras RateLimit-* del borrador del IETF.
+func setRateLimitHeaders(w http.ResponseWriter, d ratelimit.Decision) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// allowBatchItem consume un token del límite de elementos de lote para un
+// elemento del cliente client.
+func (h *Handler) allowBatchItem(client string) bool {
+	if h.batchLimit == nil {
+		return true
+	}
+	return h.batchLimit.Allow(client).Allowed
+}
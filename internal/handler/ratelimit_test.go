@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/config"
+	"github.com/jackparradev/url-inteligente/internal/ratelimit"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+// slowLimit apenas repone tokens durante un test: solo cuenta la ráfaga.
+func slowLimit(burst int) *ratelimit.Limiter {
+	return ratelimit.New(ratelimit.Limit{Rate: 0.001, Burst: burst})
+}
+
+func newRateLimitedMux(opts ...HandlerOption) (*http.ServeMux, *Handler) {
+	h := NewHandler(service.NewShortener(service.NewStorage()), opts...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", h.Require(auth.ScopeCreate, h.LimitCreate(h.ShortenURL)))
+	mux.HandleFunc("POST /api/v1/shorten/batch", h.Require(auth.ScopeCreate, h.LimitCreate(h.ShortenBatch)))
+	mux.HandleFunc("/", h.LimitRedirect(h.RedirectURL))
+	return mux, h
+}
+
+func TestHandler_LimitCreate(t *testing.T) {
+	mux, _ := newRateLimitedMux(WithRateLimits(slowLimit(2), nil))
+
+	for i := 0; i < 2; i++ {
+		rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected request %d within burst to succeed, got %d", i, rr.Code)
+		}
+		if got := rr.Header().Get("RateLimit-Remaining"); got != []string{"1", "0"}[i] {
+			t.Errorf("Expected RateLimit-Remaining %d, got %q", 1-i, got)
+		}
+	}
+
+	rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"})
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+	if got := rr.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected RateLimit-Limit 2, got %q", got)
+	}
+	if rr.Header().Get("RateLimit-Reset") == "" {
+		t.Error("Expected RateLimit-Reset header")
+	}
+	var response ErrorResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	if response.Error != "Rate limit exceeded" {
+		t.Errorf("Expected rate limit error, got %q", response.Error)
+	}
+
+	// Otra IP tiene su propio límite
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url":"https://www.example.com"}`))
+	req.RemoteAddr = "198.51.100.7:4321"
+	other := httptest.NewRecorder()
+	mux.ServeHTTP(other, req)
+	if other.Code != http.StatusCreated {
+		t.Errorf("Expected another IP to be allowed, got %d", other.Code)
+	}
+}
+
+func TestHandler_LimitCreate_ByAPIKey(t *testing.T) {
+	keys, err := auth.Open(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatalf("Error opening key store: %v", err)
+	}
+	mux, _ := newRateLimitedMux(WithAuth(keys), WithRateLimits(slowLimit(1), nil))
+	alice := mintToken(t, keys, "alice", auth.ScopeCreate)
+	bob := mintToken(t, keys, "bob", auth.ScopeCreate)
+
+	// Misma IP, claves distintas: cada clave tiene su límite
+	if rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"}, withToken(alice)); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"}, withToken(alice)); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+	if rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"}, withToken(bob)); rr.Code != http.StatusCreated {
+		t.Errorf("Expected another key to be allowed, got %d", rr.Code)
+	}
+
+	// Las peticiones sin clave se rechazan antes de gastar el límite de nadie
+	if rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"}); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestHandler_LimitCreate_Batch(t *testing.T) {
+	mux, _ := newRateLimitedMux(WithRateLimits(slowLimit(1), nil), WithBatchRateLimit(slowLimit(3)))
+
+	body := strings.Repeat(`{"url":"https://www.example.com"}`+"\n", 5)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten/batch", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	dec := json.NewDecoder(rr.Body)
+	for index := 0; index < 5; index++ {
+		var result BatchResult
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("Error decoding result %d: %v", index, err)
+		}
+		want := http.StatusCreated
+		if index >= 3 {
+			want = http.StatusTooManyRequests
+		}
+		if result.Status != want {
+			t.Errorf("Expected item %d status %d, got %d", index, want, result.Status)
+		}
+	}
+
+	// El lote entero pagó una sola petición del límite de creación
+	if rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"}); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the batch to use up the create limit, got %d", rr.Code)
+	}
+}
+
+func TestHandler_LimitCreate_BatchWithDefaults(t *testing.T) {
+	cfg := config.Default()
+	mux, _ := newRateLimitedMux(
+		WithMaxBatchSize(cfg.BatchMaxSize),
+		WithRateLimits(ratelimit.New(ratelimit.Limit{Rate: cfg.CreateRateLimit, Burst: cfg.CreateRateBurst}), nil),
+		WithBatchRateLimit(ratelimit.New(ratelimit.Limit{Rate: cfg.BatchRateLimit, Burst: cfg.BatchRateBurst})))
+
+	// Un lote del tamaño máximo pasa entero con los límites por defecto
+	var body strings.Builder
+	for i := 0; i < cfg.BatchMaxSize; i++ {
+		fmt.Fprintf(&body, `{"url":"https://www.example.com/%d"}`+"\n", i)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten/batch", strings.NewReader(body.String()))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	dec := json.NewDecoder(rr.Body)
+	created := 0
+	for dec.More() {
+		var result BatchResult
+		if err := dec.Decode(&result); err != nil {
+			t.Fatalf("Error decoding result: %v", err)
+		}
+		if result.Status == http.StatusCreated {
+			created++
+		}
+	}
+	if created != cfg.BatchMaxSize {
+		t.Errorf("Expected %d items created, got %d", cfg.BatchMaxSize, created)
+	}
+}
+
+func TestHandler_LimitRedirect(t *testing.T) {
+	mux, h := newRateLimitedMux(WithRateLimits(nil, slowLimit(1)))
+	link, _, _ := h.shortener.Create("https://www.example.com", service.CreateOptions{})
+
+	if rr := serve(mux, http.MethodGet, "/"+link.ShortCode, nil); rr.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, got %d", rr.Code)
+	}
+	rr := serve(mux, http.MethodGet, "/"+link.ShortCode, nil)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+
+	// Sin limitador de creación, crear no está limitado
+	for i := 0; i < 3; i++ {
+		if rr := serve(mux, http.MethodPost, "/shorten", ShortenRequest{URL: "https://www.example.com"}); rr.Header().Get("RateLimit-Limit") != "" {
+			t.Fatal("Expected no rate limit headers without a create limiter")
+		}
+	}
+}
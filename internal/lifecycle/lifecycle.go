@@ -0,0 +1,193 @@
+// Package lifecycle arranca el servidor HTTP y lo apaga de forma ordenada:
+// deja de aceptar conexiones, espera a que terminen las peticiones en curso
+// y después detiene los componentes registrados.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDraining lo devuelve Ready mientras el servidor se apaga.
+var ErrDraining = errors.New("server is shutting down")
+
+// closer es un componente que hay que detener al apagar.
+type closer struct {
+	name string
+	fn   func(context.Context) error
+}
+
+// App coordina el apagado del servidor y de los componentes registrados con
+// OnStop. Es seguro para uso concurrente.
+type App struct {
+	// timeout acota tanto la espera de las peticiones en curso como la
+	// detención de los componentes.
+	timeout time.Duration
+	// drainDelay es cuánto se sigue atendiendo tras la señal, informando de
+	// que no se está listo, antes de dejar de aceptar conexiones.
+	drainDelay time.Duration
+	logger     *slog.Logger
+
+	draining atomic.Bool
+
+	mu      sync.Mutex
+	closers []closer
+}
+
+// Option configura opciones opcionales de la App.
+type Option func(*App)
+
+// WithDrainDelay retrasa el cierre del listener tras la señal de apagado.
+// Durante ese tiempo Ready devuelve ErrDraining, así el balanceador deja de
+// enviar tráfico antes de que las conexiones nuevas empiecen a rechazarse.
+func WithDrainDelay(d time.Duration) Option {
+	return func(a *App) {
+		a.drainDelay = d
+	}
+}
+
+// New crea una App cuyo apagado espera como mucho timeout en cada fase.
+// Un logger nil usa slog.Default().
+func New(timeout time.Duration, logger *slog.Logger, opts ...Option) *App {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	a := &App{timeout: timeout, logger: logger}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Ready devuelve ErrDraining desde que empieza el apagado, para que la sonda
+// de disponibilidad falle mientras se drenan las peticiones.
+func (a *App) Ready() error {
+	if a.draining.Load() {
+		return ErrDraining
+	}
+	return nil
+}
+
+// OnStop registra fn para ejecutarla al apagar. Los componentes se detienen
+// en orden inverso al de registro, como los defer: lo primero que se inicia
+// (p. ej. el storage) es lo último que se detiene.
+func (a *App) OnStop(name string, fn func(context.Context) error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closers = append(a.closers, closer{name: name, fn: fn})
+}
+
+// OnStopFunc registra una función de parada que no devuelve error ni admite
+// contexto, como los Stop de las goroutines de fondo.
+func (a *App) OnStopFunc(name string, fn func()) {
+	a.OnStop(name, func(context.Context) error {
+		fn()
+		return nil
+	})
+}
+
+// Serve atiende server en ln hasta que ctx se cancela o el servidor falla.
+// Entonces deja de aceptar conexiones, espera a las peticiones en curso y
+// detiene los componentes. Devuelve los errores de todo el proceso.
+func (a *App) Serve(ctx context.Context, server *http.Server, ln net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ln)
+	}()
+
+	var errs []error
+	select {
+	case err := <-serveErr:
+		// El servidor terminó sin que nadie lo pidiera
+		errs = append(errs, fmt.Errorf("serving: %w", err))
+	case <-ctx.Done():
+		a.draining.Store(true)
+		if a.drainDelay > 0 {
+			a.logger.Info("apagando: dejando de anunciarse como disponible", "delay", a.drainDelay.String())
+			a.wait(serveErr)
+		}
+		a.logger.Info("apagando: esperando a las peticiones en curso", "timeout", a.timeout.String())
+		if err := a.drain(server); err != nil {
+			errs = append(errs, err)
+		}
+		if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Errorf("serving: %w", err))
+		}
+	}
+
+	if err := a.Stop(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// wait sigue atendiendo durante drainDelay, salvo que el servidor falle antes.
+func (a *App) wait(serveErr chan error) {
+	timer := time.NewTimer(a.drainDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case err := <-serveErr:
+		// Serve ya terminó; se devuelve el error para que Serve lo recoja
+		// después de drenar
+		serveErr <- err
+	}
+}
+
+// drain espera a que terminen las peticiones en curso. Si no terminan en el
+// plazo, cierra las conexiones que queden.
+func (a *App) drain(server *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return fmt.Errorf("draining requests: %w", err)
+	}
+	return nil
+}
+
+// Stop detiene los componentes registrados en orden inverso, cada uno con el
+// plazo de la App. Un componente que no termina a tiempo se abandona y se
+// sigue con el resto. Llamarlo más de una vez no hace nada.
+func (a *App) Stop() error {
+	a.mu.Lock()
+	closers := a.closers
+	a.closers = nil
+	a.mu.Unlock()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		c := closers[i]
+		start := time.Now()
+		if err := a.stopOne(c); err != nil {
+			a.logger.Error("error deteniendo componente", "component", c.name, "error", err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", c.name, err))
+			continue
+		}
+		a.logger.Debug("componente detenido", "component", c.name, "duration", time.Since(start).String())
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) stopOne(c closer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
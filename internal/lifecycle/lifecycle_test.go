@@ -0,0 +1,222 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newApp(timeout time.Duration) *App {
+	return New(timeout, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	return ln
+}
+
+func TestApp_Serve_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Write([]byte("ok"))
+	})}
+
+	app := newApp(5 * time.Second)
+	var stopped []string
+	app.OnStopFunc("storage", func() { stopped = append(stopped, "storage") })
+	app.OnStopFunc("reaper", func() { stopped = append(stopped, "reaper") })
+
+	ln := listen(t)
+	url := "http://" + ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- app.Serve(ctx, server, ln) }()
+
+	// Tres peticiones aceptadas y bloqueadas en el handler
+	var wg sync.WaitGroup
+	statuses := make(chan int, 3)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(url)
+			if err != nil {
+				t.Errorf("Expected in-flight request to complete, got %v", err)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	for range 3 {
+		<-started
+	}
+
+	cancel()
+	// Las conexiones nuevas se rechazan en cuanto empieza el apagado
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("Expected listener to close after shutdown started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case <-served:
+		t.Fatal("Expected Serve to wait for in-flight requests")
+	default:
+	}
+
+	close(release)
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", status)
+		}
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+	if want := []string{"reaper", "storage"}; !reflect.DeepEqual(stopped, want) {
+		t.Errorf("Expected closers in reverse order %v, got %v", want, stopped)
+	}
+}
+
+func TestApp_Serve_DrainDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+
+	app := newApp(50 * time.Millisecond)
+	closed := false
+	app.OnStopFunc("storage", func() { closed = true })
+
+	ln := listen(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- app.Serve(ctx, server, ln) }()
+	go http.Get("http://" + ln.Addr().String())
+	<-started
+
+	cancel()
+	select {
+	case err := <-served:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected drain deadline error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Serve to give up after the drain deadline")
+	}
+	if !closed {
+		t.Error("Expected closers to run after the drain deadline")
+	}
+}
+
+func TestApp_Serve_DrainDelay(t *testing.T) {
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	app := New(time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)), WithDrainDelay(200*time.Millisecond))
+	if err := app.Ready(); err != nil {
+		t.Fatalf("Expected app to be ready, got %v", err)
+	}
+
+	ln := listen(t)
+	url := "http://" + ln.Addr().String()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- app.Serve(ctx, server, ln) }()
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for app.Ready() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected app to report draining after shutdown started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !errors.Is(app.Ready(), ErrDraining) {
+		t.Errorf("Expected ErrDraining, got %v", app.Ready())
+	}
+	// Durante el retraso se siguen aceptando peticiones nuevas
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Expected requests during the drain delay to be served, got %v", err)
+	}
+	resp.Body.Close()
+
+	if err := <-served; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}
+
+func TestApp_Serve_ServerError(t *testing.T) {
+	app := newApp(time.Second)
+	closed := false
+	app.OnStopFunc("storage", func() { closed = true })
+
+	ln := listen(t)
+	ln.Close()
+	err := app.Serve(context.Background(), &http.Server{}, ln)
+	if err == nil {
+		t.Error("Expected error when the listener fails")
+	}
+	if !closed {
+		t.Error("Expected closers to run when the server fails")
+	}
+}
+
+func TestApp_Stop(t *testing.T) {
+	app := newApp(50 * time.Millisecond)
+	var order []string
+	app.OnStopFunc("first", func() { order = append(order, "first") })
+	hang := make(chan struct{})
+	defer close(hang)
+	app.OnStop("hangs", func(context.Context) error {
+		<-hang // ignora el plazo
+		return nil
+	})
+	app.OnStop("fails", func(context.Context) error {
+		order = append(order, "fails")
+		return errors.New("flush failed")
+	})
+
+	err := app.Stop()
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected timeout of the hanging closer, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "stopping fails: flush failed") {
+		t.Errorf("Expected failing closer error, got %v", err)
+	}
+	// Un componente que falla o no termina no impide detener el resto
+	if want := []string{"fails", "first"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected order %v, got %v", want, order)
+	}
+
+	if err := app.Stop(); err != nil {
+		t.Errorf("Expected second Stop to be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// AccessLog envuelve next (normalmente el http.ServeMux) para asignar un
+// identificador a cada petición y registrar una línea de acceso al terminar.
+// El identificador se toma de la cabecera X-Request-ID si es válido o se
+// genera, y se devuelve en la respuesta.
+func AccessLog(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := NewContext(r.Context(), id)
+		req := r.WithContext(ctx)
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, req)
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			// ServeMux rellena Pattern en la petición que recibe
+			slog.String("route", req.Pattern),
+			slog.Int("status", recorder.status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes", recorder.bytes),
+			slog.String("client_ip", clientIP(r)),
+		}
+		if code := infoFrom(ctx).code; code != "" {
+			attrs = append(attrs, slog.String("code", code))
+		}
+		logger.LogAttrs(ctx, accessLevel(recorder.status), "petición", attrs...)
+	})
+}
+
+// accessLevel registra los errores del servidor como tales para poder filtrarlos.
+func accessLevel(status int) slog.Level {
+	if status >= http.StatusInternalServerError {
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// responseRecorder guarda el código de estado y los bytes de la respuesta.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = code, true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap permite a http.ResponseController llegar al ResponseWriter original.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
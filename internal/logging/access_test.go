@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newAccessLog(t *testing.T) (http.Handler, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "json", "info")
+	if err != nil {
+		t.Fatalf("Error creating logger: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{code}", func(w http.ResponseWriter, r *http.Request) {
+		SetShortCode(r.Context(), r.PathValue("code"))
+		if RequestID(r.Context()) == "" {
+			t.Error("Expected request id in handler context")
+		}
+		w.WriteHeader(http.StatusFound)
+		w.Write([]byte("found"))
+	})
+	return AccessLog(logger, mux), &buf
+}
+
+func TestAccessLog(t *testing.T) {
+	handler, buf := newAccessLog(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.RemoteAddr = "203.0.113.9:5555"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	id := rr.Header().Get(RequestIDHeader)
+	if len(id) != 16 {
+		t.Fatalf("Expected generated request id, got %q", id)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected JSON access log, got %q", buf.String())
+	}
+	want := map[string]any{
+		"msg":        "petición",
+		"method":     "GET",
+		"path":       "/abc123",
+		"route":      "GET /{code}",
+		"status":     float64(302),
+		"bytes":      float64(5),
+		"client_ip":  "203.0.113.9",
+		"code":       "abc123",
+		"request_id": id,
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, record[key])
+		}
+	}
+	if _, ok := record["latency_ms"]; !ok {
+		t.Error("Expected latency_ms in access log")
+	}
+}
+
+func TestAccessLog_RequestIDHeader(t *testing.T) {
+	handler, _ := newAccessLog(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set(RequestIDHeader, "upstream-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get(RequestIDHeader); got != "upstream-42" {
+		t.Errorf("Expected incoming request id to be kept, got %q", got)
+	}
+
+	// Un identificador inválido se reemplaza en lugar de llegar a los logs
+	req = httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set(RequestIDHeader, "bad id\twith spaces")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if got := rr.Header().Get(RequestIDHeader); got == "bad id\twith spaces" || len(got) != 16 {
+		t.Errorf("Expected invalid request id to be replaced, got %q", got)
+	}
+}
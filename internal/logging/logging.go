@@ -0,0 +1,120 @@
+// Package logging configura los logs estructurados con log/slog: el logger de
+// la aplicación, el log de acceso HTTP y el identificador de petición que
+// correlaciona todas las líneas de una misma petición.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// RequestIDHeader es la cabecera de la que se toma y en la que se devuelve el
+// identificador de petición.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength acota los identificadores que envía el cliente.
+const maxRequestIDLength = 128
+
+// requestInfo son los datos de la petición que viajan en el contexto. Es un
+// puntero para que los handlers puedan completarlo (p. ej. con el código
+// resuelto) y el log de acceso lo vea.
+type requestInfo struct {
+	id   string
+	code string
+}
+
+type contextKey struct{}
+
+// NewContext devuelve una copia de ctx con el identificador de petición id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestInfo{id: id})
+}
+
+func infoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(contextKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID devuelve el identificador de petición de ctx, o vacío si no hay.
+func RequestID(ctx context.Context) string {
+	if info := infoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// SetShortCode anota en ctx el código corto que resolvió la petición, para
+// que aparezca en el log de acceso. Sin identificador de petición no hace nada.
+func SetShortCode(ctx context.Context, code string) {
+	if info := infoFrom(ctx); info != nil {
+		info.code = code
+	}
+}
+
+// NewRequestID genera un identificador aleatorio de 16 caracteres hexadecimales.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID acepta identificadores cortos de caracteres visibles, para
+// que un cliente no pueda inyectar líneas o cabeceras en los logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// contextHandler añade a cada registro el identificador de petición del
+// contexto con el que se emite, así la capa de servicio no necesita conocerlo.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// NewLogger crea el logger de la aplicación. format es "json" o "text"
+// (logfmt) y level "debug", "info", "warn" o "error".
+func NewLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "json", "warn")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx := NewContext(context.Background(), "req-1")
+	logger.InfoContext(ctx, "descartado")
+	logger.WarnContext(ctx, "aviso", "key", "abc")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %q", buf.String())
+	}
+	if record["msg"] != "aviso" || record["key"] != "abc" {
+		t.Errorf("Unexpected record %v", record)
+	}
+	if record["request_id"] != "req-1" {
+		t.Errorf("Expected request_id from context, got %v", record["request_id"])
+	}
+
+	// Los atributos añadidos con With conservan el identificador
+	buf.Reset()
+	logger.With("component", "test").WarnContext(ctx, "otro")
+	if !strings.Contains(buf.String(), `"request_id":"req-1"`) {
+		t.Errorf("Expected request_id through With, got %q", buf.String())
+	}
+}
+
+func TestNewLogger_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := NewLogger(&buf, "text", "debug")
+	logger.DebugContext(context.Background(), "hola", "status", 200)
+
+	if !strings.Contains(buf.String(), "level=DEBUG msg=hola status=200") {
+		t.Errorf("Expected logfmt output, got %q", buf.String())
+	}
+}
+
+func TestNewLogger_Errors(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := NewLogger(&bytes.Buffer{}, "json", "trace"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := RequestID(context.Background()); id != "" {
+		t.Errorf("Expected empty id without context value, got %q", id)
+	}
+	// Sin identificador en el contexto no hay dónde anotar el código
+	SetShortCode(context.Background(), "abc")
+
+	if id := NewRequestID(); len(id) != 16 || id == NewRequestID() {
+		t.Errorf("Expected random 16-char ids, got %q", id)
+	}
+
+	tests := map[string]bool{
+		"abc-123":                    true,
+		"":                           false,
+		"has space":                  false,
+		"line\nbreak":                false,
+		strings.Repeat("a", 129):     false,
+		"0f8fad5b-d9cb-469f-a165-70": true,
+	}
+	for id, want := range tests {
+		if got := validRequestID(id); got != want {
+			t.Errorf("validRequestID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
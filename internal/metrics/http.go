@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPMetrics mide las peticiones HTTP por ruta y código de estado.
+type HTTPMetrics struct {
+	requests *Counter
+	duration *Histogram
+}
+
+// NewHTTPMetrics registra http_requests_total y http_request_duration_seconds.
+func (r *Registry) NewHTTPMetrics() *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: r.NewCounter("http_requests_total", "Total HTTP requests by route and status.", "route", "status"),
+		duration: r.NewHistogram("http_request_duration_seconds", "HTTP request latency by route and status.", DefaultBuckets, "route", "status"),
+	}
+}
+
+// Instrument envuelve un http.ServeMux (o cualquier handler que rellene
+// r.Pattern) y mide cada petición. La ruta es el patrón registrado, no la
+// URL, para que las etiquetas no crezcan con cada código corto.
+func (m *HTTPMetrics) Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// ServeMux rellena r.Pattern al elegir el handler
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(recorder.status)
+		m.requests.Inc(route, status)
+		m.duration.Observe(time.Since(start).Seconds(), route, status)
+	})
+}
+
+// statusRecorder guarda el código de estado de la respuesta.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = code, true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap permite a http.ResponseController llegar al ResponseWriter original,
+// p. ej. para hacer Flush en las respuestas en streaming.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
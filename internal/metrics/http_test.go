@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPMetrics_Instrument(t *testing.T) {
+	reg := NewRegistry()
+	m := reg.NewHTTPMetrics()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	handler := m.Instrument(mux)
+
+	for _, target := range []string{"/items/1", "/items/2", "/items/missing", "/other"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	// La ruta es el patrón, así que /items/1 y /items/2 comparten serie
+	if got := m.requests.Value("GET /items/{id}", "200"); got != 2 {
+		t.Errorf("Expected 2 requests for the pattern, got %d", got)
+	}
+	if got := m.requests.Value("GET /items/{id}", "404"); got != 1 {
+		t.Errorf("Expected 1 not found request, got %d", got)
+	}
+	if got := m.requests.Value("unmatched", "404"); got != 1 {
+		t.Errorf("Expected 1 unmatched request, got %d", got)
+	}
+
+	out := scrape(t, reg)
+	if !strings.Contains(out, `http_request_duration_seconds_count{route="GET /items/{id}",status="200"} 2`) {
+		t.Errorf("Expected latency histogram per route and status, got:\n%s", out)
+	}
+}
+
+func TestHTTPMetrics_PreservesFlush(t *testing.T) {
+	m := NewRegistry().NewHTTPMetrics()
+	handler := m.Instrument(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Expected Flush to reach the underlying writer, got %v", err)
+		}
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !rr.Flushed {
+		t.Error("Expected response to be flushed")
+	}
+	if got := m.requests.Value("unmatched", "202"); got != 1 {
+		t.Errorf("Expected status 202 recorded, got %d", got)
+	}
+}
@@ -0,0 +1,317 @@
+// Package metrics implementa métricas en el formato de texto de Prometheus
+// sin dependencias externas: contadores e histogramas con etiquetas, valores
+// calculados al leer y estadísticas del runtime de Go.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metric es una familia de métricas con nombre que sabe escribirse.
+type metric interface {
+	names() []string
+	write(w *bufio.Writer)
+}
+
+// Registry agrupa las métricas que se exponen juntas. Se escriben en orden de
+// registro. Es seguro para uso concurrente.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry crea un registro vacío.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register añade m. Registrar dos veces el mismo nombre es un error de
+// programación, así que provoca un panic.
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range m.names() {
+		if r.names[name] {
+			panic(fmt.Sprintf("metrics: %q registered twice", name))
+		}
+		r.names[name] = true
+	}
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo escribe todas las métricas en el formato de texto de Prometheus.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.RUnlock()
+
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+	for _, m := range metrics {
+		m.write(buf)
+	}
+	err := buf.Flush()
+	return counter.n, err
+}
+
+// Handler sirve las métricas (GET /metrics).
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteTo(w)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeHeader escribe las líneas HELP y TYPE de una familia.
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample escribe una muestra con sus etiquetas.
+func writeSample(w *bufio.Writer, name string, labels []string, values []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabel(values[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatValue(value))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// seriesKey une los valores de las etiquetas en una clave de map.
+func seriesKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// family es la parte común de las métricas con etiquetas: las series por
+// combinación de valores, ordenadas al escribir para una salida estable.
+type family[S any] struct {
+	name   string
+	help   string
+	labels []string
+	newS   func() *S
+
+	mu     sync.RWMutex
+	series map[string]*labeled[S]
+}
+
+type labeled[S any] struct {
+	values []string
+	s      *S
+}
+
+func (f *family[S]) get(values []string) *S {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := seriesKey(values)
+	f.mu.RLock()
+	l, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return l.s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if l, ok := f.series[key]; ok {
+		return l.s
+	}
+	l = &labeled[S]{values: append([]string(nil), values...), s: f.newS()}
+	f.series[key] = l
+	return l.s
+}
+
+func (f *family[S]) sorted() []*labeled[S] {
+	f.mu.RLock()
+	series := make([]*labeled[S], 0, len(f.series))
+	for _, l := range f.series {
+		series = append(series, l)
+	}
+	f.mu.RUnlock()
+	sort.Slice(series, func(i, j int) bool { return seriesKey(series[i].values) < seriesKey(series[j].values) })
+	return series
+}
+
+// Counter es un contador con etiquetas que solo crece.
+type Counter struct {
+	family[atomic.Uint64]
+}
+
+// NewCounter registra un contador con las etiquetas labels.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family[atomic.Uint64]{
+		name: name, help: help, labels: labels,
+		newS:   func() *atomic.Uint64 { return new(atomic.Uint64) },
+		series: make(map[string]*labeled[atomic.Uint64]),
+	}}
+	r.register(c)
+	return c
+}
+
+// Inc suma uno a la serie con los valores de etiqueta indicados.
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add suma n a la serie con los valores de etiqueta indicados.
+func (c *Counter) Add(n uint64, values ...string) {
+	c.get(values).Add(n)
+}
+
+// Value devuelve el valor de una serie.
+func (c *Counter) Value(values ...string) uint64 {
+	return c.get(values).Load()
+}
+
+func (c *Counter) names() []string { return []string{c.name} }
+
+func (c *Counter) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	for _, l := range c.sorted() {
+		writeSample(w, c.name, c.labels, l.values, float64(l.s.Load()))
+	}
+}
+
+// DefaultBuckets son los límites por defecto de los histogramas de latencia, en segundos.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Histogram cuenta observaciones en intervalos acumulados, con etiquetas.
+type Histogram struct {
+	family[histogramSeries]
+	buckets []float64
+}
+
+type histogramSeries struct {
+	counts []atomic.Uint64 // uno por bucket más +Inf
+	sum    atomic.Uint64   // bits de un float64
+}
+
+// NewHistogram registra un histograma con los límites buckets (ordenados de
+// menor a mayor) y las etiquetas labels.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{buckets: buckets}
+	h.family = family[histogramSeries]{
+		name: name, help: help, labels: labels,
+		newS: func() *histogramSeries {
+			return &histogramSeries{counts: make([]atomic.Uint64, len(buckets)+1)}
+		},
+		series: make(map[string]*labeled[histogramSeries]),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe añade v a la serie con los valores de etiqueta indicados.
+func (h *Histogram) Observe(v float64, values ...string) {
+	s := h.get(values)
+	// Solo se cuenta el primer bucket que contiene v; se acumulan al escribir
+	s.counts[sort.SearchFloat64s(h.buckets, v)].Add(1)
+	for {
+		old := s.sum.Load()
+		if s.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) names() []string {
+	return []string{h.name, h.name + "_bucket", h.name + "_sum", h.name + "_count"}
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	labels := append(append([]string(nil), h.labels...), "le")
+	for _, l := range h.sorted() {
+		var cumulative uint64
+		for i := range l.s.counts {
+			cumulative += l.s.counts[i].Load()
+			le := math.Inf(1)
+			if i < len(h.buckets) {
+				le = h.buckets[i]
+			}
+			writeSample(w, h.name+"_bucket", labels, append(append([]string(nil), l.values...), formatValue(le)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_sum", h.labels, l.values, math.Float64frombits(l.s.sum.Load()))
+		writeSample(w, h.name+"_count", h.labels, l.values, float64(cumulative))
+	}
+}
+
+// funcMetric es una métrica sin etiquetas cuyo valor se calcula al leerla.
+type funcMetric struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewGaugeFunc registra un valor que puede subir y bajar, calculado con fn en
+// cada lectura.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registra un contador que otro componente lleva, leído con fn
+// en cada lectura.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (m *funcMetric) names() []string { return []string{m.name} }
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, m.name, m.help, m.kind)
+	writeSample(w, m.name, nil, nil, m.fn())
+}
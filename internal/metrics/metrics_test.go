@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, reg *Registry) string {
+	t.Helper()
+	var out strings.Builder
+	if _, err := reg.WriteTo(&out); err != nil {
+		t.Fatalf("Error writing metrics: %v", err)
+	}
+	return out.String()
+}
+
+func TestRegistry_Counter(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("requests_total", "Total requests.", "route", "status")
+	c.Inc("/a", "200")
+	c.Inc("/a", "200")
+	c.Add(5, "/b", "404")
+
+	if got := c.Value("/a", "200"); got != 2 {
+		t.Errorf("Expected 2, got %d", got)
+	}
+
+	want := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{route="/a",status="200"} 2
+requests_total{route="/b",status="404"} 5
+`
+	if got := scrape(t, reg); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	reg := NewRegistry()
+	h := reg.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(3, "/a")
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 3.65
+latency_seconds_count{route="/a"} 4
+`
+	if got := scrape(t, reg); got != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistry_Funcs(t *testing.T) {
+	reg := NewRegistry()
+	value := 3.0
+	reg.NewGaugeFunc("links", "Stored links.", func() float64 { return value })
+	reg.NewCounterFunc("evictions_total", "Evictions.", func() float64 { return 7 })
+
+	value = 4
+	out := scrape(t, reg)
+	if !strings.Contains(out, "# TYPE links gauge\nlinks 4\n") {
+		t.Errorf("Expected gauge read at scrape time, got:\n%s", out)
+	}
+	if !strings.Contains(out, "# TYPE evictions_total counter\nevictions_total 7\n") {
+		t.Errorf("Expected counter func, got:\n%s", out)
+	}
+}
+
+func TestRegistry_EscapesLabelsAndHelp(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounter("escaped_total", "Line one\nline \\ two.", "value")
+	c.Inc("a \"quoted\"\\ \nvalue")
+
+	out := scrape(t, reg)
+	if !strings.Contains(out, `# HELP escaped_total Line one\nline \\ two.`) {
+		t.Errorf("Expected escaped help, got:\n%s", out)
+	}
+	if !strings.Contains(out, `escaped_total{value="a \"quoted\"\\ \nvalue"} 1`) {
+		t.Errorf("Expected escaped label, got:\n%s", out)
+	}
+}
+
+func TestRegistry_DuplicateNamePanics(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewHistogram("latency_seconds", "Latency.", DefaultBuckets)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate metric name")
+		}
+	}()
+	reg.NewGaugeFunc("latency_seconds_count", "Clashes with the histogram.", func() float64 { return 0 })
+}
+
+func TestRegistry_Runtime(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterRuntime()
+
+	out := scrape(t, reg)
+	for _, name := range []string{"go_goroutines ", "go_memstats_alloc_bytes ", "go_gc_cycles_total ", "go_info{version=\"go", "process_uptime_seconds "} {
+		if !strings.Contains(out, name) {
+			t.Errorf("Expected %q in runtime metrics", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bufio"
+	"runtime"
+	"time"
+)
+
+// runtimeMetrics exporta estadísticas del runtime de Go. Lee runtime.MemStats
+// una sola vez por lectura para que todas las cifras sean coherentes.
+type runtimeMetrics struct {
+	start time.Time
+}
+
+// RegisterRuntime registra las métricas go_* del runtime y el tiempo de
+// actividad del proceso.
+func (r *Registry) RegisterRuntime() {
+	r.register(&runtimeMetrics{start: time.Now()})
+}
+
+func (m *runtimeMetrics) names() []string {
+	return []string{
+		"go_goroutines", "go_threads", "go_info",
+		"go_memstats_alloc_bytes", "go_memstats_sys_bytes", "go_memstats_heap_objects",
+		"go_memstats_mallocs_total", "go_memstats_frees_total",
+		"go_gc_cycles_total", "go_gc_pause_seconds_total", "process_uptime_seconds",
+	}
+}
+
+func (m *runtimeMetrics) write(w *bufio.Writer) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	threads, _ := runtime.ThreadCreateProfile(nil)
+
+	gauge := func(name, help string, value float64) {
+		writeHeader(w, name, help, "gauge")
+		writeSample(w, name, nil, nil, value)
+	}
+	counter := func(name, help string, value float64) {
+		writeHeader(w, name, help, "counter")
+		writeSample(w, name, nil, nil, value)
+	}
+
+	gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	gauge("go_threads", "Number of OS threads created.", float64(threads))
+	writeHeader(w, "go_info", "Information about the Go environment.", "gauge")
+	writeSample(w, "go_info", []string{"version"}, []string{runtime.Version()}, 1)
+	gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(stats.Alloc))
+	gauge("go_memstats_sys_bytes", "Number of bytes obtained from the system.", float64(stats.Sys))
+	gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(stats.HeapObjects))
+	counter("go_memstats_mallocs_total", "Total number of mallocs.", float64(stats.Mallocs))
+	counter("go_memstats_frees_total", "Total number of frees.", float64(stats.Frees))
+	counter("go_gc_cycles_total", "Number of completed GC cycles.", float64(stats.NumGC))
+	counter("go_gc_pause_seconds_total", "Total time spent in GC stop-the-world pauses.", time.Duration(stats.PauseTotalNs).Seconds())
+	gauge("process_uptime_seconds", "Seconds since the process started.", time.Since(m.start).Seconds())
+}
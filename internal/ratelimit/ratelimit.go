@@ -0,0 +1,176 @@
+// Package ratelimit limita el ritmo de peticiones por cliente con cubetas de
+// tokens (token bucket).
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit es el ritmo permitido a cada cliente: Rate tokens por segundo con
+// ráfagas de hasta Burst peticiones. Un Rate cero o negativo no limita.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited indica si el límite está desactivado.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// fillTime es lo que tarda una cubeta vacía en llenarse.
+func (l Limit) fillTime() time.Duration {
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Decision es el resultado de consultar el limitador para una petición.
+type Decision struct {
+	// Allowed indica si la petición puede pasar.
+	Allowed bool
+	// Limit es el tamaño de la ráfaga permitida.
+	Limit int
+	// Remaining son las peticiones que aún caben en la ráfaga actual.
+	Remaining int
+	// Reset es cuánto falta para que la cubeta vuelva a estar llena.
+	Reset time.Duration
+	// RetryAfter es cuánto esperar antes de reintentar; cero si Allowed.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter reparte una cubeta de tokens a cada cliente. Las cubetas que llevan
+// sin usarse más de lo que tardan en llenarse son iguales a una nueva, así que
+// Sweep las descarta sin cambiar ninguna decisión. Es seguro para uso
+// concurrente.
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	lifecycle sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New crea un Limiter con el límite indicado. Un Burst menor que 1 se trata como 1.
+func New(limit Limit) *Limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &Limiter{limit: limit, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Limit devuelve el límite configurado.
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow consume un token de la cubeta de key si hay alguno disponible.
+func (l *Limiter) Allow(key string) Decision {
+	if l.limit.Unlimited() {
+		return Decision{Allowed: true, Limit: l.limit.Burst, Remaining: l.limit.Burst}
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(l.limit.Burst), b.tokens+elapsed.Seconds()*l.limit.Rate)
+		b.last = now
+	}
+
+	d := Decision{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((1 - b.tokens) / l.limit.Rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((float64(l.limit.Burst) - b.tokens) / l.limit.Rate)
+	return d
+}
+
+// Len devuelve el número de cubetas en memoria.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// Sweep descarta las cubetas que ya se habrían llenado y devuelve cuántas eliminó.
+func (l *Limiter) Sweep() int {
+	idle := l.limit.fillTime()
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := 0
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= idle {
+			delete(l.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Start lanza la goroutine que ejecuta Sweep cada interval. Llamarlo con el
+// Limiter ya activo o sin límite no hace nada.
+func (l *Limiter) Start(interval time.Duration) {
+	l.lifecycle.Lock()
+	defer l.lifecycle.Unlock()
+	if l.stop != nil || l.limit.Unlimited() {
+		return
+	}
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.run(interval, l.stop, l.done)
+}
+
+// Stop detiene la goroutine de limpieza y espera a que termine.
+func (l *Limiter) Stop() {
+	l.lifecycle.Lock()
+	stop, done := l.stop, l.done
+	l.stop, l.done = nil, nil
+	l.lifecycle.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (l *Limiter) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.Sweep()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestLimiter crea un Limiter con un reloj que el test avanza a mano.
+func newTestLimiter(limit Limit) (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(limit)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 1, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		d := l.Allow("alice")
+		if !d.Allowed {
+			t.Fatalf("Expected request %d within burst to be allowed", i)
+		}
+		if d.Remaining != 2-i {
+			t.Errorf("Expected %d remaining, got %d", 2-i, d.Remaining)
+		}
+	}
+
+	d := l.Allow("alice")
+	if d.Allowed {
+		t.Fatal("Expected request over burst to be rejected")
+	}
+	if d.RetryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, got %s", d.RetryAfter)
+	}
+	if d.Reset != 3*time.Second {
+		t.Errorf("Expected reset in 3s, got %s", d.Reset)
+	}
+	if d.Limit != 3 {
+		t.Errorf("Expected limit 3, got %d", d.Limit)
+	}
+
+	// Cada cliente tiene su propia cubeta
+	if !l.Allow("bob").Allowed {
+		t.Error("Expected another client to be allowed")
+	}
+
+	*now = now.Add(time.Second)
+	if !l.Allow("alice").Allowed {
+		t.Error("Expected a token to be refilled after 1s")
+	}
+	if l.Allow("alice").Allowed {
+		t.Error("Expected only one token to be refilled")
+	}
+}
+
+func TestLimiter_RefillIsCappedAtBurst(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 10, Burst: 2})
+	l.Allow("alice")
+
+	*now = now.Add(time.Hour)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if l.Allow("alice").Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Expected refill capped at burst 2, got %d allowed", allowed)
+	}
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l, _ := newTestLimiter(Limit{})
+	for i := 0; i < 100; i++ {
+		if !l.Allow("alice").Allowed {
+			t.Fatal("Expected unlimited limiter to allow everything")
+		}
+	}
+	if l.Len() != 0 {
+		t.Errorf("Expected unlimited limiter to keep no buckets, got %d", l.Len())
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 1, Burst: 10})
+	l.Allow("idle")
+	*now = now.Add(5 * time.Second)
+	l.Allow("active")
+
+	if removed := l.Sweep(); removed != 0 {
+		t.Errorf("Expected nothing swept before buckets refill, got %d", removed)
+	}
+
+	// "idle" ya se habría llenado; "active" todavía no
+	*now = now.Add(6 * time.Second)
+	if removed := l.Sweep(); removed != 1 {
+		t.Errorf("Expected 1 bucket swept, got %d", removed)
+	}
+	if l.Len() != 1 {
+		t.Errorf("Expected 1 bucket left, got %d", l.Len())
+	}
+
+	// Descartar una cubeta llena no cambia las decisiones
+	if d := l.Allow("idle"); !d.Allowed || d.Remaining != 9 {
+		t.Errorf("Expected swept client to start with a full bucket, got %+v", d)
+	}
+}
+
+func TestLimiter_StartStop(t *testing.T) {
+	l := New(Limit{Rate: 1000, Burst: 1})
+	l.Allow("alice")
+
+	l.Start(5 * time.Millisecond)
+	l.Start(5 * time.Millisecond) // idempotente
+
+	deadline := time.Now().Add(2 * time.Second)
+	for l.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected background sweep to evict idle bucket")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	l.Stop()
+	l.Stop() // idempotente
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidAlias se devuelve cuando el alias no cumple la política configurada.
+	ErrInvalidAlias = errors.New("invalid alias")
+	// ErrAliasReserved se devuelve cuando el alias es una palabra reservada.
+	ErrAliasReserved = errors.New("alias is reserved")
+	// ErrAliasTaken se devuelve cuando el alias ya está en uso.
+	ErrAliasTaken = errors.New("alias is already taken")
+)
+
+// DefaultAliasCharset son los caracteres permitidos por defecto en un alias.
+const DefaultAliasCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+
+// probeWords son las rutas de las sondas. Se reservan siempre, aunque
+// AliasPolicy.Reserved las omita, para que ningún enlace las tape.
+var probeWords = []string{"healthz", "readyz", "version"}
+
+// DefaultReservedWords son los nombres que nunca pueden reclamarse como código
+// porque colisionan con rutas del servidor.
+var DefaultReservedWords = append([]string{"shorten", "api", "admin", "health", "metrics"}, probeWords...)
+
+// AliasPolicy define qué alias personalizados se aceptan.
+type AliasPolicy struct {
+	// Charset contiene todos los caracteres permitidos.
+	Charset string
+	// MinLength y MaxLength acotan la longitud del alias (inclusive).
+	MinLength int
+	MaxLength int
+	// Reserved son palabras que no pueden reclamarse (sin distinguir
+	// mayúsculas), además de las rutas de las sondas.
+	Reserved []string
+}
+
+// DefaultAliasPolicy devuelve la política de alias por defecto.
+func DefaultAliasPolicy() AliasPolicy {
+	return AliasPolicy{
+		Charset:   DefaultAliasCharset,
+		MinLength: 3,
+		MaxLength: 32,
+		Reserved:  DefaultReservedWords,
+	}
+}
+
+// IsReserved indica si code es una palabra reservada o la ruta de una sonda.
+func (p AliasPolicy) IsReserved(code string) bool {
+	for _, words := range [][]string{p.Reserved, probeWords} {
+		for _, word := range words {
+			if strings.EqualFold(code, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate comprueba que alias cumpla la política.
+func (p AliasPolicy) Validate(alias string) error {
+	if len(alias) < p.MinLength || len(alias) > p.MaxLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, p.MinLength, p.MaxLength)
+	}
+	for _, char := range alias {
+		// "/" separa dominio y código en las claves aunque el charset lo admita
+		if char == '/' || !strings.ContainsRune(p.Charset, char) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidAlias, char)
+		}
+	}
+	if p.IsReserved(alias) {
+		return fmt.Errorf("%w: %q", ErrAliasReserved, alias)
+	}
+	return nil
+}
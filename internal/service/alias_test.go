@@ -0,0 +1,150 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestAliasPolicy_Validate(t *testing.T) {
+	policy := DefaultAliasPolicy()
+
+	valid := []string{"spring-sale", "abc", "Promo_2024", "a1b2c3"}
+	for _, alias := range valid {
+		if err := policy.Validate(alias); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", alias, err)
+		}
+	}
+
+	invalid := map[string]error{
+		"ab":         ErrInvalidAlias,
+		"has space":  ErrInvalidAlias,
+		"slash/path": ErrInvalidAlias,
+		"ñandú":      ErrInvalidAlias,
+		"this-alias-is-way-too-long-to-be-accepted": ErrInvalidAlias,
+		"shorten": ErrAliasReserved,
+		"API":     ErrAliasReserved,
+		"admin":   ErrAliasReserved,
+		"health":  ErrAliasReserved,
+		"healthz": ErrAliasReserved,
+		"readyz":  ErrAliasReserved,
+		"Version": ErrAliasReserved,
+	}
+	for alias, expected := range invalid {
+		if err := policy.Validate(alias); !errors.Is(err, expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, alias, err)
+		}
+	}
+}
+
+func TestAliasPolicy_Custom(t *testing.T) {
+	policy := AliasPolicy{Charset: "abc", MinLength: 1, MaxLength: 3, Reserved: []string{"cab"}}
+
+	if err := policy.Validate("abc"); err != nil {
+		t.Errorf("Expected abc to be valid, got %v", err)
+	}
+	if err := policy.Validate("abd"); !errors.Is(err, ErrInvalidAlias) {
+		t.Errorf("Expected ErrInvalidAlias, got %v", err)
+	}
+	if err := policy.Validate("cab"); !errors.Is(err, ErrAliasReserved) {
+		t.Errorf("Expected ErrAliasReserved, got %v", err)
+	}
+}
+
+func TestShortener_CreateWithAlias(t *testing.T) {
+	shortener := NewShortener(NewStorage())
+
+	link, _, err := shortener.Create("https://www.example.com/spring", CreateOptions{Alias: "spring-sale"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if link.ShortCode != "spring-sale" {
+		t.Errorf("Expected short code spring-sale, got %s", link.ShortCode)
+	}
+
+	longURL, exists := shortener.GetLongURL("spring-sale")
+	if !exists || longURL != "https://www.example.com/spring" {
+		t.Errorf("Expected alias to resolve, got %q (exists=%v)", longURL, exists)
+	}
+
+	// Reclamar el mismo alias de nuevo es un conflicto y no pisa el original
+	if _, _, err := shortener.Create("https://www.other.com", CreateOptions{Alias: "spring-sale"}); !errors.Is(err, ErrAliasTaken) {
+		t.Errorf("Expected ErrAliasTaken, got %v", err)
+	}
+	longURL, _ = shortener.GetLongURL("spring-sale")
+	if longURL != "https://www.example.com/spring" {
+		t.Errorf("Expected original target to be kept, got %s", longURL)
+	}
+}
+
+func TestAliasPolicy_RejectsSlash(t *testing.T) {
+	// Aunque el charset incluya "/", un alias con "/" chocaría con las claves
+	// de otros dominios
+	policy := AliasPolicy{Charset: DefaultAliasCharset + "/.", MinLength: 1, MaxLength: 32}
+	if err := policy.Validate("acme.link/promo"); !errors.Is(err, ErrInvalidAlias) {
+		t.Errorf("Expected ErrInvalidAlias, got %v", err)
+	}
+	if err := policy.Validate("acme.link"); err != nil {
+		t.Errorf("Expected alias without '/' to be valid, got %v", err)
+	}
+}
+
+func TestShortener_CreateWithReservedAlias(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithAliasPolicy(AliasPolicy{
+		Charset:   DefaultAliasCharset,
+		MinLength: 1,
+		MaxLength: 10,
+		Reserved:  []string{"blocked"},
+	}))
+
+	if _, _, err := shortener.Create("https://www.example.com", CreateOptions{Alias: "blocked"}); !errors.Is(err, ErrAliasReserved) {
+		t.Errorf("Expected ErrAliasReserved, got %v", err)
+	}
+	if _, _, err := shortener.Create("https://www.example.com", CreateOptions{Alias: "shorten"}); err != nil {
+		t.Errorf("Expected custom reserved list to replace defaults, got %v", err)
+	}
+}
+
+func TestShortener_CreateWithAliasConcurrent(t *testing.T) {
+	shortener := NewShortener(NewStorage())
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	numGoroutines := 50
+	created := 0
+
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			_, _, err := shortener.Create(fmt.Sprintf("https://test%d.com", id), CreateOptions{Alias: "contested"})
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrAliasTaken) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if created != 1 {
+		t.Errorf("Expected exactly one alias claim to succeed, got %d", created)
+	}
+}
+
+func TestAliasPolicy_ProbePathsAlwaysReserved(t *testing.T) {
+	policy := DefaultAliasPolicy()
+	policy.Reserved = []string{"custom"}
+
+	for _, alias := range []string{"custom", "healthz", "READYZ", "version"} {
+		if err := policy.Validate(alias); !errors.Is(err, ErrAliasReserved) {
+			t.Errorf("Expected %q to stay reserved, got %v", alias, err)
+		}
+	}
+	if err := policy.Validate("shorten"); err != nil {
+		t.Errorf("Expected words dropped from Reserved to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStorageFull se devuelve al guardar un enlace nuevo en un almacenamiento
+// lleno con la política EvictReject.
+var ErrStorageFull = errors.New("storage is full")
+
+// EvictionPolicy decide qué hacer al guardar un enlace en un almacenamiento lleno.
+type EvictionPolicy string
+
+const (
+	// EvictReject rechaza los enlaces nuevos con ErrStorageFull.
+	EvictReject EvictionPolicy = "reject"
+	// EvictLRU elimina los enlaces usados hace más tiempo.
+	EvictLRU EvictionPolicy = "lru"
+	// EvictOldest elimina los enlaces creados hace más tiempo.
+	EvictOldest EvictionPolicy = "oldest"
+)
+
+// Capacity limita el tamaño de un almacenamiento. Un máximo cero no limita.
+type Capacity struct {
+	// MaxEntries es el máximo de enlaces.
+	MaxEntries int
+	// MaxBytes es el máximo aproximado de memoria ocupada por los enlaces (ver linkSize).
+	MaxBytes int64
+	// Policy es la política al llenarse; vacía equivale a EvictReject.
+	Policy EvictionPolicy
+	// OnEvict, opcional, recibe las claves de los enlaces expulsados.
+	OnEvict RemovalListener
+}
+
+// Occupancy es la ocupación de un BoundedStorage, para exponerla en métricas.
+type Occupancy struct {
+	Entries    int
+	Bytes      int64
+	MaxEntries int
+	MaxBytes   int64
+	// Evictions es el total de enlaces eliminados para hacer sitio.
+	Evictions uint64
+	// Rejections es el total de enlaces rechazados por falta de sitio.
+	Rejections uint64
+}
+
+// linkOverhead aproxima lo que ocupa un enlace además de sus cadenas: la
+// entrada del map, las fechas, el índice inverso y la contabilidad propia.
+const linkOverhead = 160
+
+// linkSize es el tamaño aproximado de un enlace en memoria.
+func linkSize(link Link) int64 {
+	return int64(len(link.ShortCode) + len(link.Domain) + len(link.LongURL) + len(link.Owner) + linkOverhead)
+}
+
+// touchBufferSize es cuántos accesos pueden esperar a aplicarse a la lista LRU.
+const touchBufferSize = 1024
+
+// BoundedStorage envuelve un Storage y le impone una Capacity.
+//
+// Los enlaces se ordenan en una lista: por antigüedad con EvictOldest o por
+// uso con EvictLRU. Para que Get no necesite un bloqueo exclusivo, los
+// accesos se encolan en un canal con búfer y se aplican a la lista en la
+// siguiente escritura. Con el búfer lleno los accesos se descartan, así que
+// el orden LRU es aproximado bajo mucha carga de lecturas.
+type BoundedStorage struct {
+	inner    Storage
+	capacity Capacity
+
+	mu      sync.Mutex
+	order   *list.List // de *boundedEntry; el frente es el próximo en salir
+	entries map[string]*list.Element
+	bytes   int64
+
+	touches    chan string
+	evictions  atomic.Uint64
+	rejections atomic.Uint64
+}
+
+type boundedEntry struct {
+	key  string
+	size int64
+}
+
+// NewBoundedStorage envuelve inner con el límite capacity. Los enlaces que ya
+// contiene se ordenan por fecha de creación y, si no caben y la política
+// permite expulsar, se eliminan los sobrantes.
+func NewBoundedStorage(inner Storage, capacity Capacity) (*BoundedStorage, error) {
+	switch capacity.Policy {
+	case "":
+		capacity.Policy = EvictReject
+	case EvictReject, EvictLRU, EvictOldest:
+	default:
+		return nil, fmt.Errorf("unknown eviction policy %q", capacity.Policy)
+	}
+
+	s := &BoundedStorage{
+		inner:    inner,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		touches:  make(chan string, touchBufferSize),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links := inner.List()
+	sort.SliceStable(links, func(i, j int) bool { return links[i].CreatedAt.Before(links[j].CreatedAt) })
+	for _, link := range links {
+		s.track(link)
+	}
+	if s.capacity.Policy != EvictReject {
+		if err := s.evict(""); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Capacity devuelve el límite configurado.
+func (s *BoundedStorage) Capacity() Capacity {
+	return s.capacity
+}
+
+// Occupancy devuelve la ocupación actual.
+func (s *BoundedStorage) Occupancy() Occupancy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Occupancy{
+		Entries:    len(s.entries),
+		Bytes:      s.bytes,
+		MaxEntries: s.capacity.MaxEntries,
+		MaxBytes:   s.capacity.MaxBytes,
+		Evictions:  s.evictions.Load(),
+		Rejections: s.rejections.Load(),
+	}
+}
+
+// track registra link como el más reciente. Debe llamarse con s.mu tomado.
+func (s *BoundedStorage) track(link Link) {
+	key := link.Key()
+	s.untrack(key)
+	entry := &boundedEntry{key: key, size: linkSize(link)}
+	s.entries[key] = s.order.PushBack(entry)
+	s.bytes += entry.size
+}
+
+// untrack olvida key. Debe llamarse con s.mu tomado.
+func (s *BoundedStorage) untrack(key string) {
+	if elem, ok := s.entries[key]; ok {
+		s.bytes -= elem.Value.(*boundedEntry).size
+		s.order.Remove(elem)
+		delete(s.entries, key)
+	}
+}
+
+// touch anota un acceso a key sin bloquear. Si el búfer está lleno y nadie
+// está escribiendo, lo vacía en el momento.
+func (s *BoundedStorage) touch(key string) {
+	if s.capacity.Policy != EvictLRU {
+		return
+	}
+	select {
+	case s.touches <- key:
+	default:
+		if s.mu.TryLock() {
+			s.drainTouches()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// drainTouches aplica los accesos pendientes a la lista. Debe llamarse con s.mu tomado.
+func (s *BoundedStorage) drainTouches() {
+	for {
+		select {
+		case key := <-s.touches:
+			if elem, ok := s.entries[key]; ok {
+				s.order.MoveToBack(elem)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// fits indica si cabe un enlace de size bytes además de los guardados,
+// descontando el enlace replaced si se va a reemplazar.
+func (s *BoundedStorage) fits(size int64, replaced string) bool {
+	entries, bytes := len(s.entries)+1, s.bytes+size
+	if elem, ok := s.entries[replaced]; ok {
+		entries--
+		bytes -= elem.Value.(*boundedEntry).size
+	}
+	return (s.capacity.MaxEntries <= 0 || entries <= s.capacity.MaxEntries) &&
+		(s.capacity.MaxBytes <= 0 || bytes <= s.capacity.MaxBytes)
+}
+
+// over indica si los enlaces guardados superan la capacidad.
+func (s *BoundedStorage) over() bool {
+	return (s.capacity.MaxEntries > 0 && len(s.entries) > s.capacity.MaxEntries) ||
+		(s.capacity.MaxBytes > 0 && s.bytes > s.capacity.MaxBytes)
+}
+
+// evict elimina enlaces del frente de la lista hasta volver a la capacidad,
+// sin tocar keep. Debe llamarse con s.mu tomado.
+func (s *BoundedStorage) evict(keep string) error {
+	var evicted []string
+	defer func() {
+		if len(evicted) > 0 && s.capacity.OnEvict != nil {
+			s.capacity.OnEvict(evicted)
+		}
+	}()
+
+	for elem := s.order.Front(); elem != nil && s.over(); {
+		entry := elem.Value.(*boundedEntry)
+		next := elem.Next()
+		if entry.key != keep {
+			if err := s.inner.Delete(entry.key); err != nil && !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("evicting %q: %w", entry.key, err)
+			}
+			s.untrack(entry.key)
+			s.evictions.Add(1)
+			evicted = append(evicted, entry.key)
+		}
+		elem = next
+	}
+	return nil
+}
+
+// admit comprueba si cabe link antes de guardarlo. Con EvictReject devuelve
+// ErrStorageFull; con el resto se expulsa después de guardar. Debe llamarse
+// con s.mu tomado.
+func (s *BoundedStorage) admit(link Link) error {
+	if s.capacity.Policy == EvictReject && !s.fits(linkSize(link), link.Key()) {
+		s.rejections.Add(1)
+		return ErrStorageFull
+	}
+	return nil
+}
+
+func (s *BoundedStorage) Store(link Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainTouches()
+	if err := s.admit(link); err != nil {
+		return err
+	}
+	if err := s.inner.Store(link); err != nil {
+		return err
+	}
+	s.track(link)
+	return s.evict(link.Key())
+}
+
+func (s *BoundedStorage) StoreIfAbsent(link Link) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainTouches()
+	// Una clave ocupada no necesita sitio: se informa sin contarla como rechazo
+	if _, exists := s.entries[link.Key()]; exists {
+		return false, nil
+	}
+	if err := s.admit(link); err != nil {
+		return false, err
+	}
+	stored, err := s.inner.StoreIfAbsent(link)
+	if err != nil || !stored {
+		return stored, err
+	}
+	s.track(link)
+	return true, s.evict(link.Key())
+}
+
+func (s *BoundedStorage) Get(key string) (Link, bool) {
+	link, exists := s.inner.Get(key)
+	if exists {
+		s.touch(key)
+	}
+	return link, exists
+}
+
+func (s *BoundedStorage) Exists(key string) bool {
+	return s.inner.Exists(key)
+}
+
+func (s *BoundedStorage) LookupURL(domain, longURL string) (Link, bool) {
+	link, exists := s.inner.LookupURL(domain, longURL)
+	if exists {
+		s.touch(link.Key())
+	}
+	return link, exists
+}
+
+func (s *BoundedStorage) Update(key string, fn func(Link) (Link, error)) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainTouches()
+	updated, err := s.inner.Update(key, func(link Link) (Link, error) {
+		updated, err := fn(link)
+		if err != nil {
+			return Link{}, err
+		}
+		updated.ShortCode, updated.Domain = link.ShortCode, link.Domain
+		return updated, s.admit(updated)
+	})
+	if err != nil {
+		return Link{}, err
+	}
+
+	// Con EvictOldest el enlace conserva su posición
+	if elem, ok := s.entries[key]; ok && s.capacity.Policy == EvictOldest {
+		entry := elem.Value.(*boundedEntry)
+		s.bytes += linkSize(updated) - entry.size
+		entry.size = linkSize(updated)
+	} else {
+		s.track(updated)
+	}
+	return updated, s.evict(key)
+}
+
+func (s *BoundedStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.inner.Delete(key); err != nil {
+		return err
+	}
+	s.untrack(key)
+	return nil
+}
+
+func (s *BoundedStorage) DeleteIf(key string, fn func(Link) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.inner.DeleteIf(key, fn); err != nil {
+		return err
+	}
+	s.untrack(key)
+	return nil
+}
+
+func (s *BoundedStorage) DeleteExpired(now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed, err := s.inner.DeleteExpired(now)
+	for _, key := range removed {
+		s.untrack(key)
+	}
+	return removed, err
+}
+
+func (s *BoundedStorage) List() []Link {
+	return s.inner.List()
+}
+
+func (s *BoundedStorage) ListPage(after string, limit int) []Link {
+	return s.inner.ListPage(after, limit)
+}
+
+func (s *BoundedStorage) ListOwnedPage(owner, after string, limit int) []Link {
+	return s.inner.ListOwnedPage(owner, after, limit)
+}
+
+func (s *BoundedStorage) Count() int {
+	return s.inner.Count()
+}
+
+func (s *BoundedStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, s.inner)
+}
+
+func (s *BoundedStorage) Close() error {
+	return s.inner.Close()
+}
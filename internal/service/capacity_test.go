@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newBounded(t *testing.T, capacity Capacity) *BoundedStorage {
+	t.Helper()
+	storage, err := NewBoundedStorage(NewMemoryStorage(), capacity)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return storage
+}
+
+func storeCodes(t *testing.T, storage Storage, codes ...string) {
+	t.Helper()
+	for _, code := range codes {
+		if _, err := storage.StoreIfAbsent(Link{ShortCode: code, LongURL: "https://" + code + ".com"}); err != nil {
+			t.Fatalf("Error storing %s: %v", code, err)
+		}
+	}
+}
+
+func TestBoundedStorage_Conformance(t *testing.T) {
+	for _, policy := range []EvictionPolicy{EvictReject, EvictLRU, EvictOldest} {
+		t.Run(string(policy), func(t *testing.T) {
+			runStorageConformance(t, func(t *testing.T) Storage {
+				return newBounded(t, Capacity{MaxEntries: 1000, Policy: policy})
+			})
+		})
+	}
+}
+
+func TestBoundedStorage_Reject(t *testing.T) {
+	storage := newBounded(t, Capacity{MaxEntries: 2, Policy: EvictReject})
+	storeCodes(t, storage, "a", "b")
+
+	if _, err := storage.StoreIfAbsent(Link{ShortCode: "c", LongURL: "https://c.com"}); !errors.Is(err, ErrStorageFull) {
+		t.Fatalf("Expected ErrStorageFull, got %v", err)
+	}
+	if storage.Exists("c") {
+		t.Error("Expected rejected link not to be stored")
+	}
+
+	// Reemplazar o tocar un enlace existente no necesita sitio
+	if stored, err := storage.StoreIfAbsent(Link{ShortCode: "a", LongURL: "https://x.com"}); stored || err != nil {
+		t.Errorf("Expected taken key to report false without error, got %v, %v", stored, err)
+	}
+	if err := storage.Store(Link{ShortCode: "a", LongURL: "https://x.com"}); err != nil {
+		t.Errorf("Expected overwrite to fit, got %v", err)
+	}
+
+	// Al liberar sitio se vuelve a aceptar
+	storage.Delete("b")
+	storeCodes(t, storage, "c")
+
+	occupancy := storage.Occupancy()
+	if occupancy.Entries != 2 || occupancy.Rejections != 1 || occupancy.Evictions != 0 {
+		t.Errorf("Unexpected occupancy %+v", occupancy)
+	}
+}
+
+func TestBoundedStorage_LRU(t *testing.T) {
+	storage := newBounded(t, Capacity{MaxEntries: 3, Policy: EvictLRU})
+	storeCodes(t, storage, "a", "b", "c")
+
+	// Usar "a" la convierte en la más reciente: sale "b"
+	storage.Get("a")
+	storeCodes(t, storage, "d")
+
+	if storage.Exists("b") {
+		t.Error("Expected least recently used link to be evicted")
+	}
+	for _, code := range []string{"a", "c", "d"} {
+		if !storage.Exists(code) {
+			t.Errorf("Expected %s to be kept", code)
+		}
+	}
+	if occupancy := storage.Occupancy(); occupancy.Entries != 3 || occupancy.Evictions != 1 {
+		t.Errorf("Unexpected occupancy %+v", occupancy)
+	}
+}
+
+func TestBoundedStorage_Oldest(t *testing.T) {
+	storage := newBounded(t, Capacity{MaxEntries: 3, Policy: EvictOldest})
+	storeCodes(t, storage, "a", "b", "c")
+
+	// Con EvictOldest el uso no cuenta, ni tampoco editar el enlace
+	storage.Get("a")
+	storage.Update("a", func(link Link) (Link, error) {
+		link.LongURL = "https://new.com"
+		return link, nil
+	})
+	storeCodes(t, storage, "d")
+
+	if storage.Exists("a") {
+		t.Error("Expected oldest link to be evicted")
+	}
+	if !storage.Exists("b") || !storage.Exists("d") {
+		t.Error("Expected newer links to be kept")
+	}
+}
+
+func TestBoundedStorage_OnEvict(t *testing.T) {
+	var evicted []string
+	storage := newBounded(t, Capacity{MaxEntries: 2, Policy: EvictOldest, OnEvict: func(keys []string) {
+		evicted = append(evicted, keys...)
+	}})
+	storeCodes(t, storage, "a", "b", "c", "d")
+
+	if fmt.Sprint(evicted) != "[a b]" {
+		t.Errorf("Expected [a b] evicted, got %v", evicted)
+	}
+
+	// Borrar o purgar no es expulsar
+	storage.Delete("c")
+	if fmt.Sprint(evicted) != "[a b]" {
+		t.Errorf("Expected no eviction on delete, got %v", evicted)
+	}
+}
+
+func TestBoundedStorage_MaxBytes(t *testing.T) {
+	link := Link{ShortCode: "a", LongURL: "https://a.com"}
+	storage := newBounded(t, Capacity{MaxBytes: 2 * linkSize(link), Policy: EvictReject})
+	storeCodes(t, storage, "a", "b")
+
+	if occupancy := storage.Occupancy(); occupancy.Bytes != 2*linkSize(link) {
+		t.Errorf("Expected %d bytes, got %d", 2*linkSize(link), occupancy.Bytes)
+	}
+	if _, err := storage.StoreIfAbsent(Link{ShortCode: "c", LongURL: "https://c.com"}); !errors.Is(err, ErrStorageFull) {
+		t.Errorf("Expected ErrStorageFull, got %v", err)
+	}
+
+	// Una edición que no cabe se rechaza sin modificar el enlace
+	_, err := storage.Update("a", func(link Link) (Link, error) {
+		link.LongURL = "https://a.com/" + strings.Repeat("x", 100)
+		return link, nil
+	})
+	if !errors.Is(err, ErrStorageFull) {
+		t.Errorf("Expected ErrStorageFull on growing update, got %v", err)
+	}
+	if got, _ := storage.Get("a"); got.LongURL != "https://a.com" {
+		t.Errorf("Expected link unchanged, got %s", got.LongURL)
+	}
+}
+
+func TestBoundedStorage_DeleteExpiredFreesSpace(t *testing.T) {
+	now := time.Now()
+	storage := newBounded(t, Capacity{MaxEntries: 2, Policy: EvictReject})
+	storage.Store(Link{ShortCode: "old", LongURL: "https://old.com", ExpiresAt: now.Add(-time.Second)})
+	storeCodes(t, storage, "a")
+
+	if removed, err := storage.DeleteExpired(now); len(removed) != 1 || removed[0] != "old" || err != nil {
+		t.Fatalf("Expected old to be removed, got %v, %v", removed, err)
+	}
+	if occupancy := storage.Occupancy(); occupancy.Entries != 1 {
+		t.Errorf("Expected 1 entry after purge, got %d", occupancy.Entries)
+	}
+	storeCodes(t, storage, "b")
+}
+
+func TestNewBoundedStorage_EvictsExisting(t *testing.T) {
+	inner := NewMemoryStorage()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		inner.Store(Link{ShortCode: fmt.Sprintf("c%d", i), LongURL: "https://example.com", CreatedAt: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	storage, err := NewBoundedStorage(inner, Capacity{MaxEntries: 3, Policy: EvictOldest})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if inner.Count() != 3 || inner.Exists("c0") || inner.Exists("c1") {
+		t.Errorf("Expected the 2 oldest links to be evicted, got %v", inner.List())
+	}
+	if storage.Occupancy().Evictions != 2 {
+		t.Errorf("Expected 2 evictions, got %d", storage.Occupancy().Evictions)
+	}
+
+	if _, err := NewBoundedStorage(inner, Capacity{Policy: "lfu"}); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestBoundedStorage_ConcurrentAccess(t *testing.T) {
+	storage := newBounded(t, Capacity{MaxEntries: 50, Policy: EvictLRU})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				code := fmt.Sprintf("g%d-%d", g, i)
+				storage.StoreIfAbsent(Link{ShortCode: code, LongURL: "https://example.com"})
+				storage.Get(code)
+				storage.Get(fmt.Sprintf("g%d-%d", g, i/2))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if occupancy := storage.Occupancy(); occupancy.Entries != 50 || storage.Count() != 50 {
+		t.Errorf("Expected storage capped at 50, got %+v (count %d)", occupancy, storage.Count())
+	}
+}
@@ -0,0 +1,476 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runStorageConformance ejecuta la batería de pruebas que todo backend de
+// Storage debe pasar. newStorage debe devolver un almacenamiento vacío.
+func runStorageConformance(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Run("StoreAndGet", func(t *testing.T) {
+		storage := newStorage(t)
+
+		if err := storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		link, exists := storage.Get("abc123")
+		if !exists {
+			t.Fatal("Expected URL to exist in storage")
+		}
+		if link.LongURL != "https://www.google.com" {
+			t.Errorf("Expected https://www.google.com, got %s", link.LongURL)
+		}
+	})
+
+	t.Run("GetNonExistent", func(t *testing.T) {
+		storage := newStorage(t)
+
+		if _, exists := storage.Get("nonexistent"); exists {
+			t.Error("Expected false for non-existent key")
+		}
+	})
+
+	t.Run("StoreOverwrites", func(t *testing.T) {
+		storage := newStorage(t)
+
+		storage.Store(Link{ShortCode: "abc123", LongURL: "https://first.com"})
+		storage.Store(Link{ShortCode: "abc123", LongURL: "https://second.com"})
+
+		link, _ := storage.Get("abc123")
+		if link.LongURL != "https://second.com" {
+			t.Errorf("Expected https://second.com, got %s", link.LongURL)
+		}
+		if storage.Count() != 1 {
+			t.Errorf("Expected count 1, got %d", storage.Count())
+		}
+	})
+
+	t.Run("StoreIfAbsent", func(t *testing.T) {
+		storage := newStorage(t)
+
+		stored, err := storage.StoreIfAbsent(Link{ShortCode: "abc123", LongURL: "https://first.com"})
+		if err != nil || !stored {
+			t.Fatalf("Expected first store to succeed, got stored=%v err=%v", stored, err)
+		}
+
+		stored, err = storage.StoreIfAbsent(Link{ShortCode: "abc123", LongURL: "https://second.com"})
+		if err != nil || stored {
+			t.Errorf("Expected second store to be rejected, got stored=%v err=%v", stored, err)
+		}
+
+		link, _ := storage.Get("abc123")
+		if link.LongURL != "https://first.com" {
+			t.Errorf("Expected original mapping to be kept, got %s", link.LongURL)
+		}
+	})
+
+	t.Run("StoreIfAbsentConcurrent", func(t *testing.T) {
+		storage := newStorage(t)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		numGoroutines := 50
+		winners := []string{}
+
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				defer wg.Done()
+				longURL := fmt.Sprintf("https://test%d.com", id)
+				if stored, _ := storage.StoreIfAbsent(Link{ShortCode: "same", LongURL: longURL}); stored {
+					mu.Lock()
+					winners = append(winners, longURL)
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if len(winners) != 1 {
+			t.Fatalf("Expected exactly one winner, got %d", len(winners))
+		}
+		link, _ := storage.Get("same")
+		if link.LongURL != winners[0] {
+			t.Errorf("Expected stored URL %s, got %s", winners[0], link.LongURL)
+		}
+	})
+
+	t.Run("LookupURL", func(t *testing.T) {
+		storage := newStorage(t)
+
+		if _, found := storage.LookupURL("", "https://www.google.com"); found {
+			t.Error("Expected no reverse mapping in empty storage")
+		}
+
+		storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+		storage.StoreIfAbsent(Link{ShortCode: "def456", LongURL: "https://www.github.com"})
+
+		link, found := storage.LookupURL("", "https://www.google.com")
+		if !found || link.ShortCode != "abc123" {
+			t.Errorf("Expected abc123, got %q (found=%v)", link.ShortCode, found)
+		}
+		link, found = storage.LookupURL("", "https://www.github.com")
+		if !found || link.ShortCode != "def456" {
+			t.Errorf("Expected def456, got %q (found=%v)", link.ShortCode, found)
+		}
+
+		// Reasignar el código a otra URL debe limpiar la entrada anterior
+		storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.example.com"})
+		if _, found := storage.LookupURL("", "https://www.google.com"); found {
+			t.Error("Expected stale reverse mapping to be removed on overwrite")
+		}
+
+		storage.Delete("def456")
+		if _, found := storage.LookupURL("", "https://www.github.com"); found {
+			t.Error("Expected reverse mapping to be removed on delete")
+		}
+
+		// Los enlaces con expiración no se indexan ni desplazan al permanente
+		storage.Store(Link{ShortCode: "permanent", LongURL: "https://a.com"})
+		storage.Store(Link{ShortCode: "expiring", LongURL: "https://a.com", ExpiresAt: time.Unix(1, 0)})
+		link, found = storage.LookupURL("", "https://a.com")
+		if !found || link.ShortCode != "permanent" {
+			t.Errorf("Expected permanent link to stay indexed, got %q (found=%v)", link.ShortCode, found)
+		}
+	})
+
+	t.Run("Exists", func(t *testing.T) {
+		storage := newStorage(t)
+
+		if storage.Exists("test123") {
+			t.Error("Expected false for non-existent key")
+		}
+		storage.Store(Link{ShortCode: "test123", LongURL: "https://test.com"})
+		if !storage.Exists("test123") {
+			t.Error("Expected true for existing key")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		storage := newStorage(t)
+
+		storage.Store(Link{ShortCode: "test123", LongURL: "https://test.com"})
+		if err := storage.Delete("test123"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if storage.Exists("test123") {
+			t.Error("Expected key to be deleted")
+		}
+		if err := storage.Delete("test123"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("DeleteIf", func(t *testing.T) {
+		storage := newStorage(t)
+
+		storage.Store(Link{ShortCode: "test123", LongURL: "https://test.com", Owner: "alice"})
+		refuse := errors.New("refused")
+		if err := storage.DeleteIf("test123", func(Link) error { return refuse }); err != refuse {
+			t.Errorf("Expected fn error, got %v", err)
+		}
+		if !storage.Exists("test123") {
+			t.Error("Expected key to be kept when fn fails")
+		}
+		var seen Link
+		if err := storage.DeleteIf("test123", func(link Link) error { seen = link; return nil }); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if seen.Owner != "alice" || storage.Exists("test123") {
+			t.Errorf("Expected key to be deleted after fn saw it, got %+v", seen)
+		}
+		if err := storage.DeleteIf("test123", func(Link) error { return nil }); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		storage := newStorage(t)
+
+		createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		storage.Store(Link{ShortCode: "abc123", LongURL: "https://old.com", CreatedAt: createdAt})
+
+		updated, err := storage.Update("abc123", func(link Link) (Link, error) {
+			link.LongURL = "https://new.com"
+			link.ShortCode = "ignored"
+			return link, nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if updated.ShortCode != "abc123" || updated.LongURL != "https://new.com" || !updated.CreatedAt.Equal(createdAt) {
+			t.Errorf("Unexpected updated link %+v", updated)
+		}
+		if link, _ := storage.Get("abc123"); link.LongURL != "https://new.com" {
+			t.Errorf("Expected stored URL https://new.com, got %s", link.LongURL)
+		}
+		if storage.Exists("ignored") {
+			t.Error("Expected Update not to change the short code")
+		}
+
+		// El índice inverso sigue al nuevo destino
+		if _, found := storage.LookupURL("", "https://old.com"); found {
+			t.Error("Expected old URL to be unindexed")
+		}
+		if link, found := storage.LookupURL("", "https://new.com"); !found || link.ShortCode != "abc123" {
+			t.Errorf("Expected new URL to be indexed, got %q (found=%v)", link.ShortCode, found)
+		}
+
+		// Un error de fn no modifica nada
+		failure := errors.New("rejected")
+		if _, err := storage.Update("abc123", func(link Link) (Link, error) {
+			return Link{}, failure
+		}); !errors.Is(err, failure) {
+			t.Errorf("Expected fn error, got %v", err)
+		}
+		if link, _ := storage.Get("abc123"); link.LongURL != "https://new.com" {
+			t.Errorf("Expected link to be unchanged after failed update, got %s", link.LongURL)
+		}
+
+		if _, err := storage.Update("missing", func(link Link) (Link, error) {
+			return link, nil
+		}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("PreservesMetadata", func(t *testing.T) {
+		storage := newStorage(t)
+
+		createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		expiresAt := createdAt.Add(time.Hour)
+		storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com", CreatedAt: createdAt, ExpiresAt: expiresAt, RedirectType: 307})
+
+		link, _ := storage.Get("abc123")
+		if !link.CreatedAt.Equal(createdAt) || !link.ExpiresAt.Equal(expiresAt) || link.RedirectType != 307 {
+			t.Errorf("Expected metadata to be preserved, got %+v", link)
+		}
+	})
+
+	t.Run("Domains", func(t *testing.T) {
+		storage := newStorage(t)
+
+		// El mismo código en dos dominios son enlaces distintos
+		storage.Store(Link{ShortCode: "abc", LongURL: "https://default.com"})
+		if stored, _ := storage.StoreIfAbsent(Link{ShortCode: "abc", Domain: "acme.link", LongURL: "https://acme.com"}); !stored {
+			t.Fatal("Expected code to be free in another domain")
+		}
+		if link, _ := storage.Get(LinkKey("acme.link", "abc")); link.LongURL != "https://acme.com" || link.Domain != "acme.link" {
+			t.Errorf("Expected acme.link link, got %+v", link)
+		}
+		if link, _ := storage.Get("abc"); link.LongURL != "https://default.com" {
+			t.Errorf("Expected default domain link, got %+v", link)
+		}
+
+		// El índice inverso es por dominio
+		storage.Store(Link{ShortCode: "xyz", Domain: "acme.link", LongURL: "https://default.com"})
+		if link, found := storage.LookupURL("", "https://default.com"); !found || link.Domain != "" {
+			t.Errorf("Expected default domain reverse mapping, got %+v", link)
+		}
+		if link, found := storage.LookupURL("acme.link", "https://default.com"); !found || link.ShortCode != "xyz" {
+			t.Errorf("Expected acme.link reverse mapping, got %+v", link)
+		}
+
+		updated, err := storage.Update(LinkKey("acme.link", "abc"), func(link Link) (Link, error) {
+			link.Domain = "other.co"
+			return link, nil
+		})
+		if err != nil || updated.Domain != "acme.link" {
+			t.Errorf("Expected domain to be immutable, got %+v (err=%v)", updated, err)
+		}
+
+		if err := storage.Delete(LinkKey("acme.link", "abc")); err != nil {
+			t.Errorf("Expected delete to succeed, got %v", err)
+		}
+		if !storage.Exists("abc") || storage.Exists(LinkKey("acme.link", "abc")) {
+			t.Error("Expected delete to affect only its domain")
+		}
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		storage := newStorage(t)
+
+		now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		storage.Store(Link{ShortCode: "expired", LongURL: "https://a.com", ExpiresAt: now.Add(-time.Minute)})
+		storage.Store(Link{ShortCode: "boundary", LongURL: "https://b.com", ExpiresAt: now})
+		storage.Store(Link{ShortCode: "future", LongURL: "https://c.com", ExpiresAt: now.Add(time.Minute)})
+		storage.Store(Link{ShortCode: "forever", LongURL: "https://d.com"})
+
+		removed, err := storage.DeleteExpired(now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		sort.Strings(removed)
+		if fmt.Sprint(removed) != "[boundary expired]" {
+			t.Errorf("Expected [boundary expired] removed, got %v", removed)
+		}
+		if storage.Exists("expired") || storage.Exists("boundary") {
+			t.Error("Expected expired links to be removed")
+		}
+		if !storage.Exists("future") || !storage.Exists("forever") {
+			t.Error("Expected live links to be kept")
+		}
+	})
+
+	t.Run("ListAndCount", func(t *testing.T) {
+		storage := newStorage(t)
+
+		if storage.Count() != 0 || len(storage.List()) != 0 {
+			t.Fatal("Expected empty storage")
+		}
+
+		storage.Store(Link{ShortCode: "c", LongURL: "https://c.com"})
+		storage.Store(Link{ShortCode: "a", LongURL: "https://a.com"})
+		storage.Store(Link{ShortCode: "b", LongURL: "https://b.com"})
+
+		if storage.Count() != 3 {
+			t.Errorf("Expected count 3, got %d", storage.Count())
+		}
+
+		links := storage.List()
+		expected := []Link{
+			{ShortCode: "a", LongURL: "https://a.com"},
+			{ShortCode: "b", LongURL: "https://b.com"},
+			{ShortCode: "c", LongURL: "https://c.com"},
+		}
+		if len(links) != len(expected) {
+			t.Fatalf("Expected %d links, got %d", len(expected), len(links))
+		}
+		for i := range expected {
+			if links[i] != expected[i] {
+				t.Errorf("Expected %+v at position %d, got %+v", expected[i], i, links[i])
+			}
+		}
+	})
+
+	t.Run("ListPage", func(t *testing.T) {
+		storage := newStorage(t)
+
+		for _, code := range []string{"e", "b", "a", "d", "c"} {
+			storage.Store(Link{ShortCode: code, LongURL: "https://" + code + ".com"})
+		}
+
+		var pages [][]string
+		after := ""
+		for {
+			page := storage.ListPage(after, 2)
+			if len(page) == 0 {
+				break
+			}
+			codes := []string{}
+			for _, link := range page {
+				codes = append(codes, link.ShortCode)
+			}
+			pages = append(pages, codes)
+			after = page[len(page)-1].ShortCode
+		}
+
+		if fmt.Sprint(pages) != "[[a b] [c d] [e]]" {
+			t.Errorf("Expected pages [[a b] [c d] [e]], got %v", pages)
+		}
+		if len(storage.ListPage("", 0)) != 0 {
+			t.Error("Expected empty page for zero limit")
+		}
+		if page := storage.ListPage("bb", 10); len(page) != 3 || page[0].ShortCode != "c" {
+			t.Errorf("Expected cursor between codes to resume at c, got %+v", page)
+		}
+	})
+
+	t.Run("ListOwnedPage", func(t *testing.T) {
+		storage := newStorage(t)
+
+		for i, code := range []string{"e", "b", "a", "d", "c", "f"} {
+			owner := "alice"
+			if i%2 == 1 {
+				owner = "bob"
+			}
+			storage.Store(Link{ShortCode: code, LongURL: "https://" + code + ".com", Owner: owner})
+		}
+
+		// alice tiene e, a y c; bob tiene b, d y f
+		var pages [][]string
+		after := ""
+		for {
+			page := storage.ListOwnedPage("alice", after, 2)
+			if len(page) == 0 {
+				break
+			}
+			codes := []string{}
+			for _, link := range page {
+				codes = append(codes, link.ShortCode)
+			}
+			pages = append(pages, codes)
+			after = page[len(page)-1].ShortCode
+		}
+
+		if fmt.Sprint(pages) != "[[a c] [e]]" {
+			t.Errorf("Expected pages [[a c] [e]], got %v", pages)
+		}
+		if page := storage.ListOwnedPage("carol", "", 10); len(page) != 0 {
+			t.Errorf("Expected no links for unknown owner, got %+v", page)
+		}
+		if len(storage.ListOwnedPage("alice", "", 0)) != 0 {
+			t.Error("Expected empty page for zero limit")
+		}
+	})
+
+	t.Run("ConcurrentAccess", func(t *testing.T) {
+		storage := newStorage(t)
+
+		var wg sync.WaitGroup
+		numGoroutines := 50
+
+		wg.Add(numGoroutines * 2)
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				defer wg.Done()
+				storage.Store(Link{ShortCode: fmt.Sprintf("code%d", id), LongURL: fmt.Sprintf("https://test%d.com", id)})
+			}(i)
+			go func(id int) {
+				defer wg.Done()
+				storage.Get(fmt.Sprintf("code%d", id))
+				storage.Exists(fmt.Sprintf("code%d", id))
+				storage.Count()
+			}(i)
+		}
+		wg.Wait()
+
+		for i := 0; i < numGoroutines; i++ {
+			link, exists := storage.Get(fmt.Sprintf("code%d", i))
+			if !exists || link.LongURL != fmt.Sprintf("https://test%d.com", i) {
+				t.Errorf("Data integrity compromised for code%d", i)
+			}
+		}
+	})
+}
+
+func TestMemoryStorage_Conformance(t *testing.T) {
+	runStorageConformance(t, func(t *testing.T) Storage {
+		return NewMemoryStorage()
+	})
+}
+
+func TestOpenStorage(t *testing.T) {
+	storage, err := OpenStorage(StorageOptions{Backend: "memory"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := storage.(*MemoryStorage); !ok {
+		t.Errorf("Expected *MemoryStorage, got %T", storage)
+	}
+
+	storage, err = OpenStorage(StorageOptions{Backend: "sharded", Shards: 8})
+	if sharded, ok := storage.(*ShardedStorage); err != nil || !ok || sharded.Shards() != 8 {
+		t.Errorf("Expected *ShardedStorage with 8 shards, got %T, %v", storage, err)
+	}
+
+	if _, err := OpenStorage(StorageOptions{Backend: "unknown"}); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShortener_DedupReusesExistingCode(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithDedup(true))
+
+	first, created, err := shortener.Create("https://www.google.com", CreateOptions{})
+	if err != nil || !created {
+		t.Fatalf("Expected first link to be created, got created=%v err=%v", created, err)
+	}
+
+	second, created, err := shortener.Create("https://www.google.com", CreateOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created {
+		t.Error("Expected second request to reuse the existing link")
+	}
+	if second.ShortCode != first.ShortCode {
+		t.Errorf("Expected same short code %s, got %s", first.ShortCode, second.ShortCode)
+	}
+
+	other, created, _ := shortener.Create("https://www.github.com", CreateOptions{})
+	if !created || other.ShortCode == first.ShortCode {
+		t.Error("Expected a different URL to get a new code")
+	}
+}
+
+func TestShortener_DedupDisabledByDefault(t *testing.T) {
+	shortener := NewShortener(NewStorage())
+
+	first, _, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	second, created, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	if !created || first.ShortCode == second.ShortCode {
+		t.Error("Expected a new code for each request without dedup")
+	}
+}
+
+func TestShortener_DedupSkipsExpiringLinks(t *testing.T) {
+	clock := newFakeClock()
+	shortener := NewShortener(NewStorage(), WithDedup(true), WithClock(clock))
+
+	expiring, _, _ := shortener.Create("https://www.google.com", CreateOptions{TTL: time.Hour})
+
+	// Un enlace permanente no debe reutilizar uno que expira
+	permanent, created, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	if !created || permanent.ShortCode == expiring.ShortCode {
+		t.Error("Expected permanent request not to reuse an expiring link")
+	}
+
+	// Una petición con expiración siempre crea un enlace nuevo
+	_, created, _ = shortener.Create("https://www.google.com", CreateOptions{TTL: time.Hour})
+	if !created {
+		t.Error("Expected expiring request to create a new link")
+	}
+
+	// Las peticiones permanentes siguientes reutilizan el permanente
+	again, created, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	if created || again.ShortCode != permanent.ShortCode {
+		t.Errorf("Expected to reuse %s, got %s (created=%v)", permanent.ShortCode, again.ShortCode, created)
+	}
+}
+
+func TestShortener_DedupMatchesEquivalentURLs(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithDedup(true))
+
+	first, _, _ := shortener.Create("https://www.google.com/search?q=go&utm_source=newsletter", CreateOptions{})
+
+	// Misma URL con otra capitalización, puerto por defecto y parámetros de seguimiento
+	second, created, err := shortener.Create("HTTPS://WWW.Google.com:443/a/../search?fbclid=x&q=go", CreateOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created || second.ShortCode != first.ShortCode {
+		t.Errorf("Expected equivalent URL to reuse %s, got %s (created=%v)", first.ShortCode, second.ShortCode, created)
+	}
+}
+
+func TestShortener_DedupIsPerDomain(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithDedup(true))
+
+	first, _, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	other, created, _ := shortener.Create("https://www.google.com", CreateOptions{Domain: "acme.link"})
+	if !created || other.Domain != "acme.link" {
+		t.Errorf("Expected a new link in acme.link, got %+v (created=%v)", other, created)
+	}
+
+	again, created, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	if created || again.ShortCode != first.ShortCode || again.Domain != "" {
+		t.Errorf("Expected to reuse %s in the default domain, got %+v", first.ShortCode, again)
+	}
+}
+
+func TestShortener_DedupIsPerOwner(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithDedup(true))
+
+	first, _, _ := shortener.Create("https://www.google.com", CreateOptions{Owner: "alice"})
+	other, created, _ := shortener.Create("https://www.google.com", CreateOptions{Owner: "bob"})
+	if !created || other.ShortCode == first.ShortCode || other.Owner != "bob" {
+		t.Errorf("Expected bob to get his own link, got %+v (created=%v)", other, created)
+	}
+}
+
+// slowLookupStorage retrasa LookupURL para que las peticiones simultáneas
+// coincidan entre la búsqueda y la creación.
+type slowLookupStorage struct {
+	Storage
+}
+
+func (s slowLookupStorage) LookupURL(domain, longURL string) (Link, bool) {
+	link, found := s.Storage.LookupURL(domain, longURL)
+	time.Sleep(time.Millisecond)
+	return link, found
+}
+
+func TestShortener_DedupConcurrent(t *testing.T) {
+	storage := NewStorage()
+	shortener := NewShortener(slowLookupStorage{storage}, WithDedup(true))
+
+	const numGoroutines = 50
+	codes := make([]string, numGoroutines)
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			link, _, err := shortener.Create("https://www.google.com", CreateOptions{})
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			codes[id] = link.ShortCode
+		}(i)
+	}
+	wg.Wait()
+
+	for _, code := range codes {
+		if code != codes[0] {
+			t.Fatalf("Expected every request to get %s, got %s", codes[0], code)
+		}
+	}
+	if count := storage.Count(); count != 1 {
+		t.Errorf("Expected a single link, got %d", count)
+	}
+}
@@ -0,0 +1,465 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy controla cuándo se fuerza a disco el log de escritura.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways sincroniza después de cada escritura.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval sincroniza en segundo plano cada FsyncInterval.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever deja la sincronización en manos del sistema operativo.
+	FsyncNever FsyncPolicy = "never"
+)
+
+const (
+	defaultFsyncInterval = time.Second
+	// defaultSnapshotMinRecords evita snapshots cuando apenas hubo escrituras.
+	defaultSnapshotMinRecords = 1
+)
+
+// FileStorageOptions configura el backend "file".
+type FileStorageOptions struct {
+	// Fsync es la política de sincronización del log.
+	Fsync FsyncPolicy
+	// FsyncInterval es el intervalo de sincronización con FsyncInterval.
+	FsyncInterval time.Duration
+	// SnapshotInterval es cada cuánto se intenta escribir un snapshot en
+	// segundo plano. Cero desactiva los snapshots periódicos.
+	SnapshotInterval time.Duration
+	// SnapshotMinRecords es el número mínimo de registros escritos desde el
+	// último snapshot para que valga la pena escribir otro.
+	SnapshotMinRecords int
+}
+
+// FileStorage es un backend durable: cada escritura se añade a un log con
+// checksum antes de aplicarse al índice en memoria, y el log se reproduce al
+// arrancar. Las lecturas se sirven desde memoria.
+//
+// El log se divide en segmentos numerados. Un snapshot con número N contiene
+// el estado de todos los segmentos <= N, que se eliminan una vez escrito; al
+// arrancar se carga el último snapshot válido y solo se reproducen los
+// segmentos posteriores.
+type FileStorage struct {
+	dir   string
+	opts  FileStorageOptions
+	index *MemoryStorage
+
+	// mu serializa las escrituras para que el orden del log coincida con el
+	// orden en que se aplican al índice.
+	mu      sync.Mutex
+	wal     *os.File
+	seq     uint64
+	records int
+	dirty   bool
+	closed  bool
+
+	// snapMu evita que dos snapshots se escriban a la vez.
+	snapMu sync.Mutex
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewFileStorage abre (o crea) el almacenamiento en dir, carga el último
+// snapshot y reproduce los segmentos del log posteriores.
+func NewFileStorage(dir string, opts FileStorageOptions) (*FileStorage, error) {
+	switch opts.Fsync {
+	case "":
+		opts.Fsync = FsyncInterval
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("unknown fsync policy %q", opts.Fsync)
+	}
+	if opts.FsyncInterval <= 0 {
+		opts.FsyncInterval = defaultFsyncInterval
+	}
+	if opts.SnapshotMinRecords <= 0 {
+		opts.SnapshotMinRecords = defaultSnapshotMinRecords
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+
+	s := &FileStorage{
+		dir:   dir,
+		opts:  opts,
+		index: NewMemoryStorage(),
+		stop:  make(chan struct{}),
+	}
+	if err := s.recover(); err != nil {
+		return nil, err
+	}
+
+	if opts.Fsync == FsyncInterval {
+		s.wg.Add(1)
+		go s.syncLoop()
+	}
+	if opts.SnapshotInterval > 0 {
+		s.wg.Add(1)
+		go s.snapshotLoop()
+	}
+
+	return s, nil
+}
+
+// recover carga el último snapshot válido, reproduce los segmentos
+// posteriores y deja abierto el último segmento para escritura.
+func (s *FileStorage) recover() error {
+	snapSeq, err := loadLatestSnapshot(s.dir, func(link Link) {
+		s.index.Store(link)
+	})
+	if err != nil {
+		return err
+	}
+
+	segments, err := listSegments(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var replayed int
+	for i, seq := range segments {
+		path := segmentPath(s.dir, seq)
+		if seq <= snapSeq {
+			// Ya cubierto por el snapshot: quedó de una compactación interrumpida
+			os.Remove(path)
+			continue
+		}
+
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("opening write-ahead log: %w", err)
+		}
+		if _, err := replayWAL(f, func(rec walRecord) {
+			s.apply(rec)
+			replayed++
+		}); err != nil {
+			f.Close()
+			return fmt.Errorf("replaying %s: %w", filepath.Base(path), err)
+		}
+
+		if i == len(segments)-1 {
+			s.wal, s.seq = f, seq
+		} else {
+			f.Close()
+		}
+	}
+	s.records = replayed
+
+	if s.wal == nil {
+		next := snapSeq + 1
+		if len(segments) > 0 && segments[len(segments)-1] >= next {
+			next = segments[len(segments)-1] + 1
+		}
+		if err := s.openSegment(next); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Storage: %d enlaces recuperados de %s (snapshot %d, %d registros del log)",
+		s.index.Count(), s.dir, snapSeq, replayed)
+	return nil
+}
+
+// openSegment crea el segmento seq y lo convierte en el segmento activo.
+func (s *FileStorage) openSegment(seq uint64) error {
+	f, err := os.OpenFile(segmentPath(s.dir, seq), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening write-ahead log: %w", err)
+	}
+	s.wal, s.seq = f, seq
+	return syncDir(s.dir)
+}
+
+// apply aplica un registro del log al índice en memoria.
+func (s *FileStorage) apply(rec walRecord) {
+	switch rec.Op {
+	case walOpPut:
+		s.index.Store(rec.Link)
+	case walOpDelete:
+		s.index.Delete(rec.Key())
+	}
+}
+
+// deleteRecord construye el registro de borrado de link. Solo guarda lo
+// necesario para reconstruir su clave.
+func deleteRecord(link Link) walRecord {
+	return walRecord{Op: walOpDelete, Link: Link{ShortCode: link.ShortCode, Domain: link.Domain}}
+}
+
+// append escribe un registro en el log. Debe llamarse con s.mu tomado.
+func (s *FileStorage) append(rec walRecord) error {
+	if s.closed {
+		return ErrClosed
+	}
+	buf, err := encodeWALRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := s.wal.Write(buf); err != nil {
+		return fmt.Errorf("writing write-ahead log: %w", err)
+	}
+	s.records++
+	if s.opts.Fsync == FsyncAlways {
+		if err := s.wal.Sync(); err != nil {
+			return fmt.Errorf("syncing write-ahead log: %w", err)
+		}
+		return nil
+	}
+	s.dirty = true
+	return nil
+}
+
+func (s *FileStorage) syncLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.opts.FsyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.dirty && !s.closed {
+				if err := s.wal.Sync(); err != nil {
+					log.Printf("Storage: error sincronizando el log: %v", err)
+				} else {
+					s.dirty = false
+				}
+			}
+			s.mu.Unlock()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *FileStorage) snapshotLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.opts.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			pending := s.records
+			s.mu.Unlock()
+			if pending < s.opts.SnapshotMinRecords {
+				continue
+			}
+			if err := s.Snapshot(); err != nil {
+				log.Printf("Storage: error escribiendo snapshot: %v", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Snapshot escribe un snapshot del estado actual y compacta los segmentos
+// del log que cubre. Las escrituras solo se bloquean mientras se rota el
+// segmento activo; la copia del índice y la escritura a disco ocurren fuera
+// del lock de escritura.
+func (s *FileStorage) Snapshot() error {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+
+	// Rotar: a partir de aquí las escrituras van a un segmento nuevo
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	covered := s.seq
+	if err := s.wal.Sync(); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("syncing write-ahead log: %w", err)
+	}
+	previous := s.wal
+	if err := s.openSegment(covered + 1); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.records = 0
+	s.dirty = false
+	s.mu.Unlock()
+	previous.Close()
+
+	// La copia puede incluir escrituras ya registradas en el segmento nuevo.
+	// No importa: reproducir put/del es idempotente y el resultado final es
+	// el mismo.
+	if err := writeSnapshot(s.dir, covered, s.index.List()); err != nil {
+		return err
+	}
+
+	return compact(s.dir, covered)
+}
+
+func (s *FileStorage) Store(link Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(walRecord{Op: walOpPut, Link: link}); err != nil {
+		return err
+	}
+	return s.index.Store(link)
+}
+
+func (s *FileStorage) StoreIfAbsent(link Link) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Todas las escrituras al índice pasan por s.mu, así que la comprobación
+	// y el guardado son atómicos.
+	if s.index.Exists(link.Key()) {
+		return false, nil
+	}
+	if err := s.append(walRecord{Op: walOpPut, Link: link}); err != nil {
+		return false, err
+	}
+	return true, s.index.Store(link)
+}
+
+func (s *FileStorage) Get(key string) (Link, bool) {
+	return s.index.Get(key)
+}
+
+func (s *FileStorage) Exists(key string) bool {
+	return s.index.Exists(key)
+}
+
+func (s *FileStorage) LookupURL(domain, longURL string) (Link, bool) {
+	return s.index.LookupURL(domain, longURL)
+}
+
+func (s *FileStorage) Update(key string, fn func(Link) (Link, error)) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, exists := s.index.Get(key)
+	if !exists {
+		return Link{}, ErrNotFound
+	}
+	updated, err := fn(link)
+	if err != nil {
+		return Link{}, err
+	}
+	updated.ShortCode, updated.Domain = link.ShortCode, link.Domain
+	if err := s.append(walRecord{Op: walOpPut, Link: updated}); err != nil {
+		return Link{}, err
+	}
+	return updated, s.index.Store(updated)
+}
+
+func (s *FileStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, exists := s.index.Get(key)
+	if !exists {
+		return ErrNotFound
+	}
+	if err := s.append(deleteRecord(link)); err != nil {
+		return err
+	}
+	return s.index.Delete(key)
+}
+
+func (s *FileStorage) DeleteIf(key string, fn func(Link) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, exists := s.index.Get(key)
+	if !exists {
+		return ErrNotFound
+	}
+	if err := fn(link); err != nil {
+		return err
+	}
+	if err := s.append(deleteRecord(link)); err != nil {
+		return err
+	}
+	return s.index.Delete(key)
+}
+
+func (s *FileStorage) DeleteExpired(now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []string
+	for _, link := range s.index.expired(now) {
+		if err := s.append(deleteRecord(link)); err != nil {
+			return removed, err
+		}
+		s.index.Delete(link.Key())
+		removed = append(removed, link.Key())
+	}
+	return removed, nil
+}
+
+func (s *FileStorage) List() []Link {
+	return s.index.List()
+}
+
+func (s *FileStorage) ListPage(after string, limit int) []Link {
+	return s.index.ListPage(after, limit)
+}
+
+func (s *FileStorage) ListOwnedPage(owner, after string, limit int) []Link {
+	return s.index.ListOwnedPage(owner, after, limit)
+}
+
+func (s *FileStorage) Count() int {
+	return s.index.Count()
+}
+
+// Ping comprueba que el almacenamiento sigue abierto y que el segmento
+// activo del log y el directorio de datos siguen accesibles.
+func (s *FileStorage) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if _, err := s.wal.Stat(); err != nil {
+		return fmt.Errorf("checking write-ahead log: %w", err)
+	}
+	if _, err := os.Stat(s.dir); err != nil {
+		return fmt.Errorf("checking storage directory: %w", err)
+	}
+	return nil
+}
+
+// Close detiene las tareas en segundo plano, fuerza el log a disco y
+// cierra el archivo.
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.wg.Wait()
+
+	// Esperar a un snapshot en curso antes de cerrar el segmento activo
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.opts.Fsync != FsyncNever {
+		if err := s.wal.Sync(); err != nil {
+			s.wal.Close()
+			return fmt.Errorf("syncing write-ahead log: %w", err)
+		}
+	}
+	return s.wal.Close()
+}
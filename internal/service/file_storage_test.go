@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestFileStorage(t *testing.T, dir string, policy FsyncPolicy) *FileStorage {
+	t.Helper()
+	storage, err := NewFileStorage(dir, FileStorageOptions{Fsync: policy, FsyncInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Error opening file storage: %v", err)
+	}
+	return storage
+}
+
+func TestFileStorage_Conformance(t *testing.T) {
+	for _, policy := range []FsyncPolicy{FsyncAlways, FsyncInterval, FsyncNever} {
+		t.Run(string(policy), func(t *testing.T) {
+			runStorageConformance(t, func(t *testing.T) Storage {
+				storage := openTestFileStorage(t, t.TempDir(), policy)
+				t.Cleanup(func() { storage.Close() })
+				return storage
+			})
+		})
+	}
+}
+
+func TestFileStorage_ReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	storage.Store(Link{ShortCode: "def456", LongURL: "https://www.github.com"})
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.example.com"})
+	storage.Delete("def456")
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Error closing storage: %v", err)
+	}
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+
+	link, exists := reopened.Get("abc123")
+	if !exists || link.LongURL != "https://www.example.com" {
+		t.Errorf("Expected https://www.example.com, got %q (exists=%v)", link.LongURL, exists)
+	}
+	if reopened.Exists("def456") {
+		t.Error("Expected deleted code to stay deleted after replay")
+	}
+	if reopened.Count() != 1 {
+		t.Errorf("Expected count 1, got %d", reopened.Count())
+	}
+}
+
+func TestFileStorage_TruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	storage.Close()
+
+	walPath := segmentPath(dir, 1)
+	info, _ := os.Stat(walPath)
+	validSize := info.Size()
+
+	// Simular una escritura interrumpida: registro a medias al final
+	record, _ := encodeWALRecord(walRecord{Op: walOpPut, Link: Link{ShortCode: "def456", LongURL: "https://www.github.com"}})
+	appendBytes(t, walPath, record[:len(record)-3])
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	if reopened.Exists("def456") {
+		t.Error("Expected torn record to be discarded")
+	}
+	if !reopened.Exists("abc123") {
+		t.Error("Expected valid record to survive")
+	}
+
+	info, _ = os.Stat(walPath)
+	if info.Size() != validSize {
+		t.Errorf("Expected log truncated to %d bytes, got %d", validSize, info.Size())
+	}
+
+	// El log debe seguir aceptando escrituras tras la recuperación
+	reopened.Store(Link{ShortCode: "ghi789", LongURL: "https://www.example.com"})
+	reopened.Close()
+
+	again := openTestFileStorage(t, dir, FsyncAlways)
+	defer again.Close()
+	if !again.Exists("ghi789") || !again.Exists("abc123") {
+		t.Error("Expected records written after recovery to be replayed")
+	}
+}
+
+func TestFileStorage_TruncatesCorruptFinalRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	storage.Close()
+
+	walPath := segmentPath(dir, 1)
+	record, _ := encodeWALRecord(walRecord{Op: walOpPut, Link: Link{ShortCode: "def456", LongURL: "https://www.github.com"}})
+	record[len(record)-1] ^= 0xff
+	appendBytes(t, walPath, record)
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+	if reopened.Exists("def456") {
+		t.Error("Expected corrupt record to be discarded")
+	}
+	if !reopened.Exists("abc123") {
+		t.Error("Expected valid record to survive")
+	}
+}
+
+func TestFileStorage_CorruptionInMiddleFails(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	storage.Store(Link{ShortCode: "def456", LongURL: "https://www.github.com"})
+	storage.Close()
+
+	// Corromper el payload del primer registro
+	walPath := segmentPath(dir, 1)
+	data, _ := os.ReadFile(walPath)
+	data[walHeaderSize+2] ^= 0xff
+	os.WriteFile(walPath, data, 0o644)
+
+	if _, err := NewFileStorage(dir, FileStorageOptions{Fsync: FsyncAlways}); !errors.Is(err, errWALCorrupt) {
+		t.Errorf("Expected errWALCorrupt, got %v", err)
+	}
+}
+
+func TestFileStorage_ClosedRejectsWrites(t *testing.T) {
+	storage := openTestFileStorage(t, t.TempDir(), FsyncInterval)
+	storage.Close()
+
+	if err := storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Errorf("Expected second Close to be a no-op, got %v", err)
+	}
+}
+
+func TestFileStorage_Ping(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	storage := openTestFileStorage(t, dir, FsyncInterval)
+	ctx := context.Background()
+
+	if err := Ping(ctx, storage); err != nil {
+		t.Errorf("Expected open storage to be reachable, got %v", err)
+	}
+	// Los backends en memoria no implementan Pinger y siempre responden
+	if err := Ping(ctx, NewMemoryStorage()); err != nil {
+		t.Errorf("Expected memory storage to be reachable, got %v", err)
+	}
+
+	os.RemoveAll(dir)
+	if err := Ping(ctx, storage); err == nil {
+		t.Error("Expected error after removing the data directory")
+	}
+	storage.Close()
+	if err := Ping(ctx, storage); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestNewFileStorage_InvalidPolicy(t *testing.T) {
+	if _, err := NewFileStorage(t.TempDir(), FileStorageOptions{Fsync: "sometimes"}); err == nil {
+		t.Error("Expected error for unknown fsync policy")
+	}
+}
+
+func appendBytes(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Error opening %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Error writing %s: %v", path, err)
+	}
+}
+
+func TestFileStorage_SnapshotCompactsLog(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	storage.Store(Link{ShortCode: "def456", LongURL: "https://www.github.com"})
+
+	if err := storage.Snapshot(); err != nil {
+		t.Fatalf("Error writing snapshot: %v", err)
+	}
+
+	// El segmento cubierto por el snapshot debe haberse eliminado
+	if _, err := os.Stat(segmentPath(dir, 1)); !os.IsNotExist(err) {
+		t.Error("Expected covered segment to be compacted")
+	}
+	if _, err := os.Stat(snapshotPath(dir, 1)); err != nil {
+		t.Errorf("Expected snapshot file to exist: %v", err)
+	}
+
+	// Escrituras posteriores van al segmento siguiente (la cola del log)
+	storage.Store(Link{ShortCode: "ghi789", LongURL: "https://www.example.com"})
+	storage.Delete("abc123")
+	storage.Close()
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+
+	if reopened.Exists("abc123") {
+		t.Error("Expected delete in log tail to be applied over snapshot")
+	}
+	if !reopened.Exists("def456") || !reopened.Exists("ghi789") {
+		t.Error("Expected snapshot and log tail to be restored")
+	}
+	if reopened.Count() != 2 {
+		t.Errorf("Expected count 2, got %d", reopened.Count())
+	}
+}
+
+func TestFileStorage_CorruptSnapshotIgnored(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+	storage.Close()
+
+	// Un snapshot corrupto (p. ej. escrito a medias) no debe impedir el arranque
+	os.WriteFile(snapshotPath(dir, 5), []byte(snapshotMagic+"garbage"), 0o644)
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+	if !reopened.Exists("abc123") {
+		t.Error("Expected log to be replayed when snapshot is corrupt")
+	}
+}
+
+func TestFileStorage_BackgroundSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	storage, err := NewFileStorage(dir, FileStorageOptions{
+		Fsync:            FsyncNever,
+		SnapshotInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Error opening file storage: %v", err)
+	}
+	defer storage.Close()
+
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		snapshots, _ := listSeqs(dir, snapshotPrefix, snapshotSuffix)
+		if len(snapshots) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a background snapshot to be written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Store y Get siguen funcionando mientras corren los snapshots
+	storage.Store(Link{ShortCode: "def456", LongURL: "https://www.github.com"})
+	if _, exists := storage.Get("abc123"); !exists {
+		t.Error("Expected URL to exist")
+	}
+}
+
+func TestFileStorage_MigratesLegacyLog(t *testing.T) {
+	dir := t.TempDir()
+
+	record, _ := encodeWALRecord(walRecord{Op: walOpPut, Link: Link{ShortCode: "abc123", LongURL: "https://www.google.com"}})
+	os.WriteFile(filepath.Join(dir, legacyWALFileName), record, 0o644)
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	defer storage.Close()
+	if !storage.Exists("abc123") {
+		t.Error("Expected legacy log to be replayed")
+	}
+}
+
+func TestFileStorage_DeleteExpiredIsDurable(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com", ExpiresAt: now.Add(-time.Minute)})
+	storage.Store(Link{ShortCode: "def456", LongURL: "https://www.github.com", ExpiresAt: now.Add(time.Minute)})
+	storage.DeleteExpired(now)
+	storage.Close()
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+	if reopened.Exists("abc123") {
+		t.Error("Expected purged link to stay purged after replay")
+	}
+	link, exists := reopened.Get("def456")
+	if !exists || !link.ExpiresAt.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected expiry to survive replay, got %+v", link)
+	}
+}
+
+func TestFileStorage_UpdateIsDurable(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://old.com"})
+	storage.Update("abc123", func(link Link) (Link, error) {
+		link.LongURL = "https://new.com"
+		link.RedirectType = 308
+		return link, nil
+	})
+	storage.Close()
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+	if link, _ := reopened.Get("abc123"); link.LongURL != "https://new.com" || link.RedirectType != 308 {
+		t.Errorf("Expected updated link to survive replay, got %+v", link)
+	}
+}
+
+func TestFileStorage_DomainDeleteIsDurable(t *testing.T) {
+	dir := t.TempDir()
+
+	storage := openTestFileStorage(t, dir, FsyncAlways)
+	storage.Store(Link{ShortCode: "abc", LongURL: "https://default.com"})
+	storage.Store(Link{ShortCode: "abc", Domain: "acme.link", LongURL: "https://acme.com"})
+	storage.Delete(LinkKey("acme.link", "abc"))
+	storage.Close()
+
+	reopened := openTestFileStorage(t, dir, FsyncAlways)
+	defer reopened.Close()
+	if reopened.Exists(LinkKey("acme.link", "abc")) {
+		t.Error("Expected domain delete to survive replay")
+	}
+	if !reopened.Exists("abc") {
+		t.Error("Expected default domain link to survive replay")
+	}
+}
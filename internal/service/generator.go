@@ -0,0 +1,346 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"math"
+	mathrand "math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Estrategias de generación de códigos disponibles.
+const (
+	GeneratorRandom     = "random"
+	GeneratorHash       = "hash"
+	GeneratorCounter    = "counter"
+	GeneratorObfuscated = "obfuscated"
+)
+
+const base62Alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// maxBase62Length es la mayor longitud cuyo espacio de códigos (62^n) cabe en un uint64.
+const maxBase62Length = 10
+
+// uint64Base62Length es lo que ocupa el mayor uint64 en base62.
+const uint64Base62Length = 11
+
+// CodeGenerator produce candidatos a código corto. attempt empieza en 0 y
+// aumenta en cada reintento tras una colisión.
+type CodeGenerator interface {
+	Generate(longURL string, attempt int) string
+}
+
+// CodeGeneratorFunc adapta una función al interfaz CodeGenerator.
+type CodeGeneratorFunc func(longURL string, attempt int) string
+
+func (f CodeGeneratorFunc) Generate(longURL string, attempt int) string {
+	return f(longURL, attempt)
+}
+
+// GeneratorOptions selecciona y configura una estrategia de generación.
+type GeneratorOptions struct {
+	// Strategy es "random", "hash", "counter" u "obfuscated".
+	Strategy string
+	// Length es la longitud de los códigos. Para "counter" es la longitud mínima.
+	Length int
+	// Start es el primer valor de los contadores. Tras un reinicio no basta con
+	// el número de enlaces, que baja al borrarlos: ver ResumeGenerator.
+	Start uint64
+	// Key es la clave de la permutación de "obfuscated". Cero usa una aleatoria.
+	Key uint64
+}
+
+// NewCodeGenerator construye la estrategia indicada en opts.
+func NewCodeGenerator(opts GeneratorOptions) (CodeGenerator, error) {
+	switch opts.Strategy {
+	case GeneratorRandom:
+		if opts.Length < 1 {
+			return nil, fmt.Errorf("random generator length must be positive")
+		}
+		return NewRandomGenerator(opts.Length), nil
+	case "", GeneratorHash:
+		if opts.Length < 3 || opts.Length > 2*sha1.Size {
+			return nil, fmt.Errorf("hash generator length must be between 3 and %d", 2*sha1.Size)
+		}
+		return NewHashGenerator(opts.Length), nil
+	case GeneratorCounter:
+		if opts.Length < 1 || opts.Length > maxBase62Length {
+			return nil, fmt.Errorf("counter generator length must be between 1 and %d", maxBase62Length)
+		}
+		return NewCounterGenerator(opts.Start, opts.Length), nil
+	case GeneratorObfuscated:
+		if opts.Length < 1 || opts.Length > maxBase62Length {
+			return nil, fmt.Errorf("obfuscated generator length must be between 1 and %d", maxBase62Length)
+		}
+		return NewObfuscatedCounterGenerator(opts.Start, opts.Length, opts.Key), nil
+	default:
+		return nil, fmt.Errorf("unknown code generator %q", opts.Strategy)
+	}
+}
+
+// Resumer lo implementan los generadores con estado, que tras un reinicio
+// deben seguir después de los códigos que ya emitieron.
+type Resumer interface {
+	// Resume avanza el generador para que no vuelva a producir code.
+	Resume(code string)
+}
+
+// ResumeGenerator avanza g más allá de los códigos de links si g es un
+// Resumer. Los alias con el mismo formato también lo avanzan: se saltan
+// códigos, pero ninguno guardado se vuelve a generar.
+func ResumeGenerator(g CodeGenerator, links []Link) {
+	resumer, ok := g.(Resumer)
+	if !ok {
+		return
+	}
+	for _, link := range links {
+		resumer.Resume(link.ShortCode)
+	}
+}
+
+// RandomGenerator genera códigos base62 aleatorios con crypto/rand.
+// Con longitud 7 el espacio es 62^7 (~3.5e12) frente a 16^7 del hash hex.
+type RandomGenerator struct {
+	length int
+}
+
+func NewRandomGenerator(length int) *RandomGenerator {
+	return &RandomGenerator{length: length}
+}
+
+func (g *RandomGenerator) Generate(longURL string, attempt int) string {
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length+g.length/2)
+	for len(code) < g.length {
+		rand.Read(buf)
+		for _, b := range buf {
+			// Descartar bytes >= 248 (4*62) evita el sesgo del módulo
+			if b < 248 && len(code) < g.length {
+				code = append(code, base62Alphabet[b%62])
+			}
+		}
+	}
+	return string(code)
+}
+
+// HashGenerator es la estrategia original: SHA1(url+timestamp+intento)
+// truncado a hexadecimal, con dos caracteres aleatorios en los reintentos.
+type HashGenerator struct {
+	length int
+}
+
+func NewHashGenerator(length int) *HashGenerator {
+	return &HashGenerator{length: length}
+}
+
+func (g *HashGenerator) Generate(longURL string, attempt int) string {
+	// Combinar URL + timestamp + attempt para evitar colisiones
+	// Solo usando librerías estándar
+	input := fmt.Sprintf("%s%d%d", longURL, time.Now().UnixNano(), attempt)
+
+	// Hash SHA1 (librería estándar)
+	hash := sha1.Sum([]byte(input))
+
+	// Convertir a string hexadecimal y tomar los primeros g.length caracteres
+	shortCode := fmt.Sprintf("%x", hash)[:g.length]
+
+	// Si es un reintento, agregar aleatoriedad extra
+	if attempt > 0 {
+		// Agregar 2 caracteres aleatorios al final
+		randomSuffix := mathrand.Intn(256) // 0-255
+		shortCode = fmt.Sprintf("%s%02x", shortCode[:g.length-2], randomSuffix)
+	}
+
+	return shortCode
+}
+
+// CounterGenerator codifica en base62 un contador monótono. Produce los
+// códigos más cortos posibles, pero son secuenciales y fáciles de adivinar.
+type CounterGenerator struct {
+	next      atomic.Uint64
+	minLength int
+}
+
+// NewCounterGenerator crea un contador que empieza en start. Los códigos se
+// rellenan con ceros a la izquierda hasta minLength.
+func NewCounterGenerator(start uint64, minLength int) *CounterGenerator {
+	g := &CounterGenerator{minLength: minLength}
+	g.next.Store(start)
+	return g
+}
+
+func (g *CounterGenerator) Generate(longURL string, attempt int) string {
+	return encodeBase62(g.next.Add(1)-1, g.minLength)
+}
+
+// Resume avanza el contador más allá de code si es un código base62.
+func (g *CounterGenerator) Resume(code string) {
+	if n, ok := decodeBase62(code); ok && n < math.MaxUint64 {
+		advanceTo(&g.next, n+1)
+	}
+}
+
+// ObfuscatedCounterGenerator pasa el contador por una permutación biyectiva
+// del espacio [0, 62^length) antes de codificarlo, de modo que códigos
+// consecutivos no guardan relación visible entre sí pero nunca se repiten
+// hasta agotar el espacio.
+type ObfuscatedCounterGenerator struct {
+	next   atomic.Uint64
+	length int
+	perm   *feistelPermutation
+}
+
+// NewObfuscatedCounterGenerator crea el generador. Con key cero se usa una
+// clave aleatoria; para códigos reproducibles entre reinicios debe fijarse.
+func NewObfuscatedCounterGenerator(start uint64, length int, key uint64) *ObfuscatedCounterGenerator {
+	if key == 0 {
+		var buf [8]byte
+		rand.Read(buf[:])
+		key = binary.LittleEndian.Uint64(buf[:])
+	}
+	g := &ObfuscatedCounterGenerator{
+		length: length,
+		perm:   newFeistelPermutation(pow62(length), key),
+	}
+	g.next.Store(start)
+	return g
+}
+
+func (g *ObfuscatedCounterGenerator) Generate(longURL string, attempt int) string {
+	n := (g.next.Add(1) - 1) % g.perm.domain
+	return encodeBase62(g.perm.permute(n), g.length)
+}
+
+// Resume deshace la permutación de code y avanza el contador más allá. Solo
+// tiene sentido con la misma clave con la que se generó code.
+func (g *ObfuscatedCounterGenerator) Resume(code string) {
+	if len(code) != g.length {
+		return
+	}
+	if n, ok := decodeBase62(code); ok && n < g.perm.domain {
+		advanceTo(&g.next, g.perm.invert(n)+1)
+	}
+}
+
+// advanceTo sube next hasta n si está por debajo.
+func advanceTo(next *atomic.Uint64, n uint64) {
+	for {
+		current := next.Load()
+		if current >= n || next.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// feistelPermutation es una red de Feistel balanceada sobre 2*halfBits bits
+// con "cycle walking" para restringirla a [0, domain). Cada ronda es
+// invertible, así que el resultado es una biyección del dominio.
+type feistelPermutation struct {
+	domain   uint64
+	halfBits uint
+	keys     [4]uint64
+}
+
+func newFeistelPermutation(domain, key uint64) *feistelPermutation {
+	bits := uint(0)
+	for (uint64(1) << bits) < domain {
+		bits++
+	}
+	p := &feistelPermutation{domain: domain, halfBits: (bits + 1) / 2}
+	for i := range p.keys {
+		key = splitmix64(key)
+		p.keys[i] = key
+	}
+	return p
+}
+
+func (p *feistelPermutation) permute(x uint64) uint64 {
+	// Los valores fuera del dominio se vuelven a permutar hasta caer dentro
+	for {
+		x = p.encrypt(x)
+		if x < p.domain {
+			return x
+		}
+	}
+}
+
+// invert es la inversa de permute.
+func (p *feistelPermutation) invert(x uint64) uint64 {
+	for {
+		x = p.decrypt(x)
+		if x < p.domain {
+			return x
+		}
+	}
+}
+
+func (p *feistelPermutation) decrypt(x uint64) uint64 {
+	mask := uint64(1)<<p.halfBits - 1
+	left, right := x>>p.halfBits, x&mask
+	for i := len(p.keys) - 1; i >= 0; i-- {
+		left, right = right^(splitmix64(left^p.keys[i])&mask), left
+	}
+	return left<<p.halfBits | right
+}
+
+func (p *feistelPermutation) encrypt(x uint64) uint64 {
+	mask := uint64(1)<<p.halfBits - 1
+	left, right := x>>p.halfBits, x&mask
+	for _, key := range p.keys {
+		left, right = right, left^(splitmix64(right^key)&mask)
+	}
+	return left<<p.halfBits | right
+}
+
+// splitmix64 es una función de mezcla rápida con buena difusión de bits.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+func pow62(n int) uint64 {
+	result := uint64(1)
+	for i := 0; i < n; i++ {
+		result *= 62
+	}
+	return result
+}
+
+// encodeBase62 codifica n en base62 rellenando con ceros hasta minLength.
+func encodeBase62(n uint64, minLength int) string {
+	var buf [uint64Base62Length]byte
+	i := len(buf)
+	for n > 0 || i == len(buf) {
+		i--
+		buf[i] = base62Alphabet[n%62]
+		n /= 62
+	}
+	digits := buf[i:]
+	if len(digits) >= minLength {
+		return string(digits)
+	}
+	return strings.Repeat(base62Alphabet[:1], minLength-len(digits)) + string(digits)
+}
+
+// decodeBase62 es la inversa de encodeBase62. Falla si code está vacío,
+// tiene caracteres fuera del alfabeto o no cabe en un uint64.
+func decodeBase62(code string) (uint64, bool) {
+	if code == "" {
+		return 0, false
+	}
+	var n uint64
+	for i := 0; i < len(code); i++ {
+		digit := strings.IndexByte(base62Alphabet, code[i])
+		if digit < 0 || n > (math.MaxUint64-uint64(digit))/62 {
+			return 0, false
+		}
+		n = n*62 + uint64(digit)
+	}
+	return n, true
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNewCodeGenerator(t *testing.T) {
+	for _, strategy := range []string{GeneratorRandom, GeneratorHash, GeneratorCounter, GeneratorObfuscated} {
+		generator, err := NewCodeGenerator(GeneratorOptions{Strategy: strategy, Length: 7})
+		if err != nil {
+			t.Errorf("Expected no error for %s, got %v", strategy, err)
+			continue
+		}
+
+		code := generator.Generate("https://www.google.com", 0)
+		if len(code) != 7 {
+			t.Errorf("Expected %s code of length 7, got %q", strategy, code)
+		}
+		for _, char := range code {
+			if !strings.ContainsRune(base62Alphabet, char) {
+				t.Errorf("Invalid character %c in %s code %q", char, strategy, code)
+			}
+		}
+	}
+
+	invalid := []GeneratorOptions{
+		{Strategy: "unknown", Length: 7},
+		{Strategy: GeneratorRandom, Length: 0},
+		{Strategy: GeneratorHash, Length: 2},
+		{Strategy: GeneratorObfuscated, Length: 11},
+		{Strategy: GeneratorCounter, Length: 0},
+		{Strategy: GeneratorCounter, Length: 17},
+	}
+	for _, opts := range invalid {
+		if _, err := NewCodeGenerator(opts); err == nil {
+			t.Errorf("Expected error for %+v", opts)
+		}
+	}
+}
+
+func TestCounterGenerator_Monotonic(t *testing.T) {
+	generator := NewCounterGenerator(0, 1)
+
+	expected := []string{"0", "1", "2"}
+	for _, want := range expected {
+		if got := generator.Generate("", 0); got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+
+	// El relleno no tiene tope: la longitud mínima se respeta siempre
+	if got := NewCounterGenerator(5, 20).Generate("", 0); got != strings.Repeat("0", 19)+"5" {
+		t.Errorf("Expected 20 characters, got %s", got)
+	}
+
+	generator = NewCounterGenerator(61, 3)
+	if got := generator.Generate("", 0); got != "00Z" {
+		t.Errorf("Expected 00Z, got %s", got)
+	}
+	if got := generator.Generate("", 0); got != "010" {
+		t.Errorf("Expected 010, got %s", got)
+	}
+}
+
+func TestDecodeBase62(t *testing.T) {
+	for _, n := range []uint64{0, 61, 62, 1 << 40, math.MaxUint64} {
+		if got, ok := decodeBase62(encodeBase62(n, 1)); !ok || got != n {
+			t.Errorf("Expected %d to round-trip, got %d (ok=%v)", n, got, ok)
+		}
+	}
+	for _, code := range []string{"", "a-b", "zzzzzzzzzzzz"} {
+		if _, ok := decodeBase62(code); ok {
+			t.Errorf("Expected %q to be rejected", code)
+		}
+	}
+}
+
+func TestObfuscatedCounterGenerator_IsPermutation(t *testing.T) {
+	// Con longitud 2 el espacio es pequeño (62^2) y se puede recorrer entero
+	generator := NewObfuscatedCounterGenerator(0, 2, 42)
+	space := int(pow62(2))
+
+	seen := make(map[string]bool, space)
+	sequential := 0
+	previous := ""
+	for i := 0; i < space; i++ {
+		code := generator.Generate("", 0)
+		if len(code) != 2 {
+			t.Fatalf("Expected length 2, got %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("Code %s repeated before exhausting the space", code)
+		}
+		seen[code] = true
+
+		if previous != "" && code[0] == previous[0] {
+			sequential++
+		}
+		previous = code
+	}
+
+	// Códigos consecutivos no deben parecer secuenciales
+	if sequential > space/4 {
+		t.Errorf("Expected obfuscated codes to look unrelated, %d of %d shared a prefix", sequential, space)
+	}
+}
+
+func TestObfuscatedCounterGenerator_KeyDeterminism(t *testing.T) {
+	a := NewObfuscatedCounterGenerator(0, 7, 1234)
+	b := NewObfuscatedCounterGenerator(0, 7, 1234)
+	c := NewObfuscatedCounterGenerator(0, 7, 5678)
+
+	codeA, codeB, codeC := a.Generate("", 0), b.Generate("", 0), c.Generate("", 0)
+	if codeA != codeB {
+		t.Errorf("Expected same key to produce same code, got %s and %s", codeA, codeB)
+	}
+	if codeA == codeC {
+		t.Errorf("Expected different keys to produce different codes, both %s", codeA)
+	}
+}
+
+func TestEncodeBase62(t *testing.T) {
+	cases := map[uint64]string{
+		0:    "0",
+		61:   "Z",
+		62:   "10",
+		3843: "ZZ",
+	}
+	for n, want := range cases {
+		if got := encodeBase62(n, 0); got != want {
+			t.Errorf("encodeBase62(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+var benchmarkStrategies = []string{GeneratorRandom, GeneratorHash, GeneratorCounter, GeneratorObfuscated}
+
+// BenchmarkCodeGenerator mide el rendimiento de cada estrategia.
+func BenchmarkCodeGenerator(b *testing.B) {
+	for _, strategy := range benchmarkStrategies {
+		b.Run(strategy, func(b *testing.B) {
+			generator, _ := NewCodeGenerator(GeneratorOptions{Strategy: strategy, Length: DefaultShortCodeLength})
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					generator.Generate("https://www.example.com/some/long/path", 0)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkCodeGeneratorCollisions mide la tasa de colisiones de cada
+// estrategia: qué fracción de los códigos generados ya había salido antes.
+func BenchmarkCodeGeneratorCollisions(b *testing.B) {
+	for _, strategy := range benchmarkStrategies {
+		for _, length := range []int{4, DefaultShortCodeLength} {
+			b.Run(fmt.Sprintf("%s/len=%d", strategy, length), func(b *testing.B) {
+				generator, _ := NewCodeGenerator(GeneratorOptions{Strategy: strategy, Length: length})
+				seen := make(map[string]struct{}, b.N)
+				collisions := 0
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					code := generator.Generate(fmt.Sprintf("https://www.example.com/%d", i), 0)
+					if _, dup := seen[code]; dup {
+						collisions++
+					}
+					seen[code] = struct{}{}
+				}
+				b.ReportMetric(float64(collisions)/float64(b.N), "collisions/op")
+			})
+		}
+	}
+}
+
+func TestObfuscatedCounterGenerator_Resume(t *testing.T) {
+	first := NewObfuscatedCounterGenerator(0, 3, 42)
+	var last string
+	for i := 0; i < 100; i++ {
+		last = first.Generate("", 0)
+	}
+
+	// Con la misma clave, el generador reanudado sigue donde lo dejó el primero
+	resumed := NewObfuscatedCounterGenerator(0, 3, 42)
+	resumed.Resume(last)
+	if got, want := resumed.Generate("", 0), first.Generate("", 0); got != want {
+		t.Errorf("Expected resumed generator to continue with %s, got %s", want, got)
+	}
+
+	// Los códigos de otra longitud o fuera del alfabeto no lo mueven
+	resumed.Resume("ab")
+	resumed.Resume("a-b")
+	if got, want := resumed.Generate("", 0), first.Generate("", 0); got != want {
+		t.Errorf("Expected foreign codes to be ignored, got %s want %s", got, want)
+	}
+}
+
+func TestResumeGenerator_RestartAfterDelete(t *testing.T) {
+	for _, strategy := range []string{GeneratorCounter, GeneratorObfuscated} {
+		t.Run(strategy, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := GeneratorOptions{Strategy: strategy, Length: 4, Key: 42}
+
+			storage := openTestFileStorage(t, dir, FsyncAlways)
+			generator, _ := NewCodeGenerator(opts)
+			shortener := NewShortener(storage, WithGenerator(generator), WithMaxAttempts(1))
+			var codes []string
+			for i := 0; i < 5; i++ {
+				link, _, err := shortener.Create(fmt.Sprintf("https://example.com/%d", i), CreateOptions{})
+				if err != nil {
+					t.Fatalf("Error creating link %d: %v", i, err)
+				}
+				codes = append(codes, link.ShortCode)
+			}
+			// Borrar enlaces deja Count por debajo de los códigos ya emitidos
+			shortener.Delete(codes[0])
+			shortener.Delete(codes[1])
+			storage.Close()
+
+			reopened := openTestFileStorage(t, dir, FsyncAlways)
+			defer reopened.Close()
+			generator, _ = NewCodeGenerator(opts)
+			ResumeGenerator(generator, reopened.List())
+			shortener = NewShortener(reopened, WithGenerator(generator), WithMaxAttempts(1))
+			for i := 0; i < 5; i++ {
+				if _, _, err := shortener.Create(fmt.Sprintf("https://example.org/%d", i), CreateOptions{}); err != nil {
+					t.Fatalf("Expected no collision after restart, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStorage es el backend en memoria: un map protegido con sync.RWMutex.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	links map[string]Link
+	// byURL es el índice inverso (dominio, URL larga) -> clave del enlace
+	// permanente más reciente. Los enlaces con expiración no se indexan.
+	byURL map[urlKey]string
+}
+
+// urlKey identifica una URL larga dentro de un dominio en el índice inverso.
+type urlKey struct {
+	domain  string
+	longURL string
+}
+
+// NewMemoryStorage crea un almacenamiento en memoria vacío.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		links: make(map[string]Link),
+		byURL: make(map[urlKey]string),
+	}
+}
+
+// NewStorage crea el backend por defecto (en memoria).
+func NewStorage() *MemoryStorage {
+	return NewMemoryStorage()
+}
+
+// put guarda el enlace y mantiene el índice inverso. Debe llamarse con s.mu tomado.
+func (s *MemoryStorage) put(link Link) {
+	key := link.Key()
+	if previous, exists := s.links[key]; exists {
+		s.unindex(previous)
+	}
+	s.links[key] = link
+	if link.ExpiresAt.IsZero() {
+		s.byURL[urlKey{link.Domain, link.LongURL}] = key
+	}
+}
+
+// remove borra el enlace y su entrada en el índice inverso. Debe llamarse con s.mu tomado.
+func (s *MemoryStorage) remove(link Link) {
+	delete(s.links, link.Key())
+	s.unindex(link)
+}
+
+func (s *MemoryStorage) unindex(link Link) {
+	index := urlKey{link.Domain, link.LongURL}
+	if s.byURL[index] == link.Key() {
+		delete(s.byURL, index)
+	}
+}
+
+func (s *MemoryStorage) Store(link Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(link)
+	return nil
+}
+
+func (s *MemoryStorage) StoreIfAbsent(link Link) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.links[link.Key()]; exists {
+		return false, nil
+	}
+	s.put(link)
+	return true, nil
+}
+
+func (s *MemoryStorage) Get(key string) (Link, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	link, exists := s.links[key]
+	return link, exists
+}
+
+func (s *MemoryStorage) Exists(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.links[key]
+	return exists
+}
+
+func (s *MemoryStorage) LookupURL(domain, longURL string) (Link, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, exists := s.byURL[urlKey{domain, longURL}]
+	if !exists {
+		return Link{}, false
+	}
+	return s.links[key], true
+}
+
+func (s *MemoryStorage) Update(key string, fn func(Link) (Link, error)) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, exists := s.links[key]
+	if !exists {
+		return Link{}, ErrNotFound
+	}
+	updated, err := fn(link)
+	if err != nil {
+		return Link{}, err
+	}
+	updated.ShortCode, updated.Domain = link.ShortCode, link.Domain
+	s.put(updated)
+	return updated, nil
+}
+
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, exists := s.links[key]
+	if !exists {
+		return ErrNotFound
+	}
+	s.remove(link)
+	return nil
+}
+
+func (s *MemoryStorage) DeleteIf(key string, fn func(Link) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, exists := s.links[key]
+	if !exists {
+		return ErrNotFound
+	}
+	if err := fn(link); err != nil {
+		return err
+	}
+	s.remove(link)
+	return nil
+}
+
+func (s *MemoryStorage) DeleteExpired(now time.Time) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []string
+	for key, link := range s.links {
+		if link.Expired(now) {
+			s.remove(link)
+			removed = append(removed, key)
+		}
+	}
+	return removed, nil
+}
+
+// expired devuelve los enlaces expirados en now sin modificarlos.
+func (s *MemoryStorage) expired(now time.Time) []Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var links []Link
+	for _, link := range s.links {
+		if link.Expired(now) {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func (s *MemoryStorage) List() []Link {
+	s.mu.RLock()
+	links := make([]Link, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, link)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool { return links[i].Key() < links[j].Key() })
+	return links
+}
+
+func (s *MemoryStorage) ListPage(after string, limit int) []Link {
+	if limit <= 0 {
+		return []Link{}
+	}
+	return s.selectPage(newPageSelector(after, limit))
+}
+
+func (s *MemoryStorage) ListOwnedPage(owner, after string, limit int) []Link {
+	if limit <= 0 {
+		return []Link{}
+	}
+	return s.selectPage(newOwnedPageSelector(owner, after, limit))
+}
+
+// selectPage recorre el map una sola vez; el map no tiene orden, así que
+// pageSelector se queda solo con las primeras claves.
+func (s *MemoryStorage) selectPage(p *pageSelector) []Link {
+	s.mu.RLock()
+	for key, link := range s.links {
+		p.offer(key, link)
+	}
+	s.mu.RUnlock()
+	return p.page()
+}
+
+func (s *MemoryStorage) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.links)
+}
+
+// Close no hace nada: el backend en memoria no tiene recursos que liberar.
+func (s *MemoryStorage) Close() error {
+	return nil
+}
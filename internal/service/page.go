@@ -0,0 +1,79 @@
+package service
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// pageSelector reúne los limit enlaces de menor clave posteriores a after sin
+// ordenar todo el storage: guarda un montículo de máximos con los limit
+// mejores vistos hasta ahora, así una página cuesta O(n log limit) y ocupa
+// O(limit) en lugar de copiar y ordenar los n enlaces.
+type pageSelector struct {
+	after string
+	limit int
+	// owner filtra por propietario si filterOwner es true.
+	owner       string
+	filterOwner bool
+	links       linkMaxHeap
+}
+
+func newPageSelector(after string, limit int) *pageSelector {
+	return &pageSelector{after: after, limit: limit, links: make(linkMaxHeap, 0, min(limit, 1024))}
+}
+
+func newOwnedPageSelector(owner, after string, limit int) *pageSelector {
+	p := newPageSelector(after, limit)
+	p.owner, p.filterOwner = owner, true
+	return p
+}
+
+// offer considera link, guardado con la clave key, para la página.
+func (p *pageSelector) offer(key string, link Link) {
+	if key <= p.after || (p.filterOwner && link.Owner != p.owner) {
+		return
+	}
+	if len(p.links) < p.limit {
+		// Hasta llenarse no hace falta orden; al llenarse se monta el
+		// montículo de una vez (heap.Push reservaría memoria por elemento)
+		p.links = append(p.links, pageEntry{key: key, link: link})
+		if len(p.links) == p.limit {
+			heap.Init(&p.links)
+		}
+		return
+	}
+	// El montículo está lleno: solo entra si mejora al peor de la página
+	if key < p.links[0].key {
+		p.links[0] = pageEntry{key: key, link: link}
+		heap.Fix(&p.links, 0)
+	}
+}
+
+// page devuelve los enlaces seleccionados ordenados por clave.
+func (p *pageSelector) page() []Link {
+	sort.Slice(p.links, func(i, j int) bool { return p.links[i].key < p.links[j].key })
+	links := make([]Link, len(p.links))
+	for i, entry := range p.links {
+		links[i] = entry.link
+	}
+	return links
+}
+
+type pageEntry struct {
+	key  string
+	link Link
+}
+
+// linkMaxHeap implementa heap.Interface con la mayor clave en la raíz.
+type linkMaxHeap []pageEntry
+
+func (h linkMaxHeap) Len() int           { return len(h) }
+func (h linkMaxHeap) Less(i, j int) bool { return h[i].key > h[j].key }
+func (h linkMaxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *linkMaxHeap) Push(x any)        { *h = append(*h, x.(pageEntry)) }
+func (h *linkMaxHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
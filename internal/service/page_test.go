@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestPageSelector(t *testing.T) {
+	keys := make([]string, 500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%05d", rand.Intn(100000))
+	}
+
+	for _, limit := range []int{1, 7, 50, 1000} {
+		p := newPageSelector("k20000", limit)
+		for _, key := range keys {
+			p.offer(key, Link{ShortCode: key})
+		}
+
+		var want []string
+		for _, key := range keys {
+			if key > "k20000" {
+				want = append(want, key)
+			}
+		}
+		sort.Strings(want)
+		if len(want) > limit {
+			want = want[:limit]
+		}
+
+		page := p.page()
+		got := make([]string, len(page))
+		for i, link := range page {
+			got[i] = link.ShortCode
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("limit %d: expected %v, got %v", limit, want, got)
+		}
+	}
+}
+
+// TestShortener_ListOwned_BoundedWork comprueba que listar los enlaces de un
+// propietario que no tiene ninguno no copia ni recorre el storage por páginas:
+// las reservas de memoria no dependen del número de enlaces ajenos.
+func TestShortener_ListOwned_BoundedWork(t *testing.T) {
+	for _, backend := range []struct {
+		name    string
+		storage Storage
+	}{
+		{"memory", NewMemoryStorage()},
+		{"sharded", NewShardedStorage(8)},
+	} {
+		t.Run(backend.name, func(t *testing.T) {
+			for i := 0; i < 50000; i++ {
+				backend.storage.Store(Link{ShortCode: fmt.Sprintf("c%d", i), LongURL: "https://example.com", Owner: "bob"})
+			}
+			shortener := NewShortener(backend.storage)
+
+			var links []Link
+			allocs := testing.AllocsPerRun(5, func() {
+				links = shortener.ListOwned("alice", "", 51)
+			})
+			if len(links) != 0 {
+				t.Errorf("Expected no links for alice, got %d", len(links))
+			}
+			if allocs > 10 {
+				t.Errorf("Expected a constant number of allocations, got %.0f", allocs)
+			}
+
+			allocs = testing.AllocsPerRun(5, func() {
+				links = shortener.List("", 51)
+			})
+			if len(links) != 51 {
+				t.Errorf("Expected a full page, got %d", len(links))
+			}
+			if allocs > 10 {
+				t.Errorf("Expected ListPage not to copy the whole storage, got %.0f allocations", allocs)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Reaper purga periódicamente los enlaces expirados del almacenamiento.
+type Reaper struct {
+	storage  Storage
+	clock    Clock
+	interval time.Duration
+	// onRemove, opcional, recibe las claves purgadas en cada pasada.
+	onRemove RemovalListener
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ReaperOption configura opciones opcionales del Reaper.
+type ReaperOption func(*Reaper)
+
+// WithRemovalListener avisa a fn de las claves purgadas en cada pasada.
+func WithRemovalListener(fn RemovalListener) ReaperOption {
+	return func(r *Reaper) {
+		r.onRemove = fn
+	}
+}
+
+// NewReaper crea un Reaper que purga cada interval usando clock como hora actual.
+func NewReaper(storage Storage, interval time.Duration, clock Clock, opts ...ReaperOption) *Reaper {
+	if clock == nil {
+		clock = SystemClock
+	}
+	r := &Reaper{storage: storage, clock: clock, interval: interval}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Start lanza la goroutine de purga. Llamarlo con el Reaper ya activo no hace nada.
+func (r *Reaper) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(r.stop, r.done)
+}
+
+// Stop detiene la goroutine de purga y espera a que termine.
+func (r *Reaper) Stop() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Reap ejecuta una pasada de purga y devuelve cuántos enlaces eliminó.
+func (r *Reaper) Reap() int {
+	removed, err := r.storage.DeleteExpired(r.clock.Now())
+	if err != nil {
+		log.Printf("Reaper: error purgando enlaces expirados: %v", err)
+	}
+	if len(removed) > 0 && r.onRemove != nil {
+		r.onRemove(removed)
+	}
+	return len(removed)
+}
+
+func (r *Reaper) run(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Reap()
+		case <-stop:
+			return
+		}
+	}
+}
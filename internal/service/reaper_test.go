@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReaper_Reap(t *testing.T) {
+	clock := newFakeClock()
+	storage := NewStorage()
+	shortener := NewShortener(storage, WithClock(clock))
+
+	short, _, _ := shortener.Create("https://short.com", CreateOptions{TTL: time.Minute})
+	long, _, _ := shortener.Create("https://long.com", CreateOptions{TTL: time.Hour})
+	forever, _, _ := shortener.Create("https://forever.com", CreateOptions{})
+
+	reaper := NewReaper(storage, time.Minute, clock)
+
+	if removed := reaper.Reap(); removed != 0 {
+		t.Errorf("Expected nothing to reap yet, got %d", removed)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if removed := reaper.Reap(); removed != 1 {
+		t.Errorf("Expected 1 link reaped, got %d", removed)
+	}
+	if storage.Exists(short.ShortCode) {
+		t.Error("Expected expired link to be purged")
+	}
+	if !storage.Exists(long.ShortCode) || !storage.Exists(forever.ShortCode) {
+		t.Error("Expected live links to be kept")
+	}
+}
+
+func TestReaper_RemovalListener(t *testing.T) {
+	clock := newFakeClock()
+	storage := NewStorage()
+	storage.Store(Link{ShortCode: "old", LongURL: "https://old.com", ExpiresAt: clock.Now().Add(-time.Second)})
+	storage.Store(Link{ShortCode: "new", LongURL: "https://new.com"})
+
+	var calls [][]string
+	reaper := NewReaper(storage, time.Minute, clock, WithRemovalListener(func(keys []string) {
+		calls = append(calls, keys)
+	}))
+
+	reaper.Reap()
+	reaper.Reap() // sin nada que purgar no se avisa
+	if len(calls) != 1 || len(calls[0]) != 1 || calls[0][0] != "old" {
+		t.Errorf("Expected one call with [old], got %v", calls)
+	}
+}
+
+func TestReaper_StartStop(t *testing.T) {
+	clock := newFakeClock()
+	storage := NewStorage()
+	storage.Store(Link{ShortCode: "abc123", LongURL: "https://www.google.com", ExpiresAt: clock.Now().Add(-time.Second)})
+
+	reaper := NewReaper(storage, 5*time.Millisecond, clock)
+	reaper.Start()
+	reaper.Start() // idempotente
+
+	deadline := time.Now().Add(2 * time.Second)
+	for storage.Exists("abc123") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected background reaper to purge expired link")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	reaper.Stop()
+	reaper.Stop() // idempotente
+
+	// Tras Stop no debe purgar nada más
+	storage.Store(Link{ShortCode: "def456", LongURL: "https://www.github.com", ExpiresAt: clock.Now().Add(-time.Second)})
+	time.Sleep(20 * time.Millisecond)
+	if !storage.Exists("def456") {
+		t.Error("Expected reaper to be stopped")
+	}
+}
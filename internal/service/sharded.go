@@ -0,0 +1,292 @@
+package service
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ShardedStorage es un backend en memoria con las mismas garantías que
+// MemoryStorage, pero repartido en shards con su propio sync.RWMutex según el
+// hash de la clave. Así una escritura solo bloquea las lecturas de su shard y
+// las redirecciones no esperan a las creaciones.
+//
+// El índice inverso se reparte aparte, por hash de (dominio, URL), porque los
+// enlaces a una misma URL pueden caer en shards distintos. Un índice y un
+// shard de enlaces se bloquean siempre en ese orden: primero el enlace.
+type ShardedStorage struct {
+	shards  []linkShard
+	indexes []indexShard
+	mask    uint32
+}
+
+type linkShard struct {
+	mu    sync.RWMutex
+	links map[string]Link
+	// Relleno para que dos shards no compartan línea de caché
+	_ [64]byte
+}
+
+type indexShard struct {
+	mu    sync.RWMutex
+	byURL map[urlKey]string
+	_     [64]byte
+}
+
+// NewShardedStorage crea un almacenamiento vacío con n shards, redondeado a la
+// siguiente potencia de dos. Con n <= 0 usa cuatro por procesador.
+func NewShardedStorage(n int) *ShardedStorage {
+	if n <= 0 {
+		n = 4 * runtime.GOMAXPROCS(0)
+	}
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+
+	s := &ShardedStorage{
+		shards:  make([]linkShard, size),
+		indexes: make([]indexShard, size),
+		mask:    uint32(size - 1),
+	}
+	for i := range s.shards {
+		s.shards[i].links = make(map[string]Link)
+		s.indexes[i].byURL = make(map[urlKey]string)
+	}
+	return s
+}
+
+// Shards devuelve el número de shards.
+func (s *ShardedStorage) Shards() int {
+	return len(s.shards)
+}
+
+// fnv32a es el hash FNV-1a de 32 bits, sin reservar memoria.
+func fnv32a(parts ...string) uint32 {
+	hash := uint32(2166136261)
+	for _, part := range parts {
+		for i := 0; i < len(part); i++ {
+			hash ^= uint32(part[i])
+			hash *= 16777619
+		}
+		// Separador para que ("ab", "c") y ("a", "bc") no coincidan
+		hash ^= 0xff
+		hash *= 16777619
+	}
+	return hash
+}
+
+func (s *ShardedStorage) shard(key string) *linkShard {
+	return &s.shards[fnv32a(key)&s.mask]
+}
+
+func (s *ShardedStorage) index(k urlKey) *indexShard {
+	return &s.indexes[fnv32a(k.domain, k.longURL)&s.mask]
+}
+
+// put guarda el enlace y mantiene el índice inverso. Debe llamarse con el
+// shard del enlace tomado.
+func (s *ShardedStorage) put(shard *linkShard, link Link) {
+	key := link.Key()
+	if previous, exists := shard.links[key]; exists {
+		s.unindex(previous)
+	}
+	shard.links[key] = link
+	if link.ExpiresAt.IsZero() {
+		k := urlKey{link.Domain, link.LongURL}
+		index := s.index(k)
+		index.mu.Lock()
+		index.byURL[k] = key
+		index.mu.Unlock()
+	}
+}
+
+// remove borra el enlace y su entrada en el índice inverso. Debe llamarse con
+// el shard del enlace tomado.
+func (s *ShardedStorage) remove(shard *linkShard, link Link) {
+	delete(shard.links, link.Key())
+	s.unindex(link)
+}
+
+func (s *ShardedStorage) unindex(link Link) {
+	k := urlKey{link.Domain, link.LongURL}
+	index := s.index(k)
+	index.mu.Lock()
+	if index.byURL[k] == link.Key() {
+		delete(index.byURL, k)
+	}
+	index.mu.Unlock()
+}
+
+func (s *ShardedStorage) Store(link Link) error {
+	shard := s.shard(link.Key())
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	s.put(shard, link)
+	return nil
+}
+
+func (s *ShardedStorage) StoreIfAbsent(link Link) (bool, error) {
+	shard := s.shard(link.Key())
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, exists := shard.links[link.Key()]; exists {
+		return false, nil
+	}
+	s.put(shard, link)
+	return true, nil
+}
+
+func (s *ShardedStorage) Get(key string) (Link, bool) {
+	shard := s.shard(key)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	link, exists := shard.links[key]
+	return link, exists
+}
+
+func (s *ShardedStorage) Exists(key string) bool {
+	_, exists := s.Get(key)
+	return exists
+}
+
+func (s *ShardedStorage) LookupURL(domain, longURL string) (Link, bool) {
+	k := urlKey{domain, longURL}
+	index := s.index(k)
+	index.mu.RLock()
+	key, exists := index.byURL[k]
+	index.mu.RUnlock()
+	if !exists {
+		return Link{}, false
+	}
+
+	// Entre soltar el índice y leer el enlace otra escritura puede haberlo
+	// cambiado; solo vale si sigue siendo un enlace permanente a la misma URL
+	link, exists := s.Get(key)
+	if !exists || link.Domain != domain || link.LongURL != longURL || !link.ExpiresAt.IsZero() {
+		return Link{}, false
+	}
+	return link, true
+}
+
+func (s *ShardedStorage) Update(key string, fn func(Link) (Link, error)) (Link, error) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	link, exists := shard.links[key]
+	if !exists {
+		return Link{}, ErrNotFound
+	}
+	updated, err := fn(link)
+	if err != nil {
+		return Link{}, err
+	}
+	updated.ShortCode, updated.Domain = link.ShortCode, link.Domain
+	s.put(shard, updated)
+	return updated, nil
+}
+
+func (s *ShardedStorage) Delete(key string) error {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	link, exists := shard.links[key]
+	if !exists {
+		return ErrNotFound
+	}
+	s.remove(shard, link)
+	return nil
+}
+
+func (s *ShardedStorage) DeleteIf(key string, fn func(Link) error) error {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	link, exists := shard.links[key]
+	if !exists {
+		return ErrNotFound
+	}
+	if err := fn(link); err != nil {
+		return err
+	}
+	s.remove(shard, link)
+	return nil
+}
+
+// DeleteExpired recorre los shards de uno en uno, así que nunca bloquea todo
+// el almacenamiento a la vez.
+func (s *ShardedStorage) DeleteExpired(now time.Time) ([]string, error) {
+	var removed []string
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for key, link := range shard.links {
+			if link.Expired(now) {
+				s.remove(shard, link)
+				removed = append(removed, key)
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed, nil
+}
+
+func (s *ShardedStorage) List() []Link {
+	links := make([]Link, 0, s.Count())
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		for _, link := range shard.links {
+			links = append(links, link)
+		}
+		shard.mu.RUnlock()
+	}
+
+	sort.Slice(links, func(i, j int) bool { return links[i].Key() < links[j].Key() })
+	return links
+}
+
+func (s *ShardedStorage) ListPage(after string, limit int) []Link {
+	if limit <= 0 {
+		return []Link{}
+	}
+	return s.selectPage(newPageSelector(after, limit))
+}
+
+func (s *ShardedStorage) ListOwnedPage(owner, after string, limit int) []Link {
+	if limit <= 0 {
+		return []Link{}
+	}
+	return s.selectPage(newOwnedPageSelector(owner, after, limit))
+}
+
+// selectPage recorre los shards de uno en uno con el mismo pageSelector, así
+// que nunca se bloquea más de un shard a la vez.
+func (s *ShardedStorage) selectPage(p *pageSelector) []Link {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		for key, link := range shard.links {
+			p.offer(key, link)
+		}
+		shard.mu.RUnlock()
+	}
+	return p.page()
+}
+
+func (s *ShardedStorage) Count() int {
+	count := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.RLock()
+		count += len(shard.links)
+		shard.mu.RUnlock()
+	}
+	return count
+}
+
+// Close no hace nada: el backend en memoria no tiene recursos que liberar.
+func (s *ShardedStorage) Close() error {
+	return nil
+}
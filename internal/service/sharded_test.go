@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestShardedStorage_Conformance(t *testing.T) {
+	runStorageConformance(t, func(t *testing.T) Storage {
+		return NewShardedStorage(8)
+	})
+}
+
+func TestNewShardedStorage_RoundsToPowerOfTwo(t *testing.T) {
+	if got := NewShardedStorage(5).Shards(); got != 8 {
+		t.Errorf("Expected 8 shards, got %d", got)
+	}
+	if got := NewShardedStorage(0).Shards(); got < 4 {
+		t.Errorf("Expected at least 4 shards by default, got %d", got)
+	}
+}
+
+func TestShardedStorage_LookupURLAcrossShards(t *testing.T) {
+	storage := NewShardedStorage(16)
+
+	// Con 16 shards estos códigos caen en shards distintos casi seguro; el
+	// índice inverso debe quedarse siempre con el último guardado
+	for i := 0; i < 20; i++ {
+		storage.Store(Link{ShortCode: fmt.Sprintf("code%d", i), LongURL: "https://same.com"})
+		link, exists := storage.LookupURL("", "https://same.com")
+		if !exists || link.ShortCode != fmt.Sprintf("code%d", i) {
+			t.Fatalf("Expected newest link code%d, got %+v (%v)", i, link, exists)
+		}
+	}
+
+	storage.Delete("code19")
+	if _, exists := storage.LookupURL("", "https://same.com"); exists {
+		t.Error("Expected index entry to be removed with its link")
+	}
+}
+
+func TestShardedStorage_ListPageMergesShards(t *testing.T) {
+	storage := NewShardedStorage(4)
+	for i := 0; i < 50; i++ {
+		storage.Store(Link{ShortCode: fmt.Sprintf("c%02d", i), LongURL: "https://example.com"})
+	}
+
+	var keys []string
+	for after := ""; ; {
+		page := storage.ListPage(after, 7)
+		if len(page) == 0 {
+			break
+		}
+		for _, link := range page {
+			keys = append(keys, link.Key())
+		}
+		after = page[len(page)-1].Key()
+	}
+	if len(keys) != 50 {
+		t.Fatalf("Expected 50 links across pages, got %d", len(keys))
+	}
+	for i, key := range keys {
+		if key != fmt.Sprintf("c%02d", i) {
+			t.Fatalf("Expected c%02d at position %d, got %s", i, i, key)
+		}
+	}
+}
+
+func TestShardedStorage_ConcurrentSameURL(t *testing.T) {
+	storage := NewShardedStorage(8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := fmt.Sprintf("code%d", i)
+			storage.Store(Link{ShortCode: code, LongURL: "https://same.com"})
+			storage.LookupURL("", "https://same.com")
+			if i%2 == 0 {
+				storage.Delete(code)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// El índice nunca apunta a un enlace borrado ni a otra URL
+	if link, exists := storage.LookupURL("", "https://same.com"); exists {
+		if stored, ok := storage.Get(link.Key()); !ok || stored.LongURL != "https://same.com" {
+			t.Errorf("Expected index to point to a live link, got %+v", link)
+		}
+	}
+}
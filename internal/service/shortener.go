@@ -1,64 +1,542 @@
 package service
 
 import (
-	"crypto/sha1"
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/blocklist"
+	"github.com/jackparradev/url-inteligente/internal/util"
+	"github.com/jackparradev/url-inteligente/internal/validation"
 )
 
 const (
-	// Configuración hardcodeada según restricciones
-	SHORT_CODE_LENGTH = 7
-	MAX_ATTEMPTS      = 5
+	// DefaultShortCodeLength es la longitud por defecto de los códigos generados.
+	DefaultShortCodeLength = 7
+	// DefaultMaxAttempts es cuántos códigos se prueban por defecto antes de
+	// rendirse ante las colisiones.
+	DefaultMaxAttempts = 5
+
+	// dedupStripes es el número de cerrojos entre los que se reparten las
+	// URLs al deduplicar.
+	dedupStripes = 64
 )
 
+var (
+	// ErrExpired se devuelve al resolver un enlace cuya fecha de expiración ya pasó.
+	ErrExpired = errors.New("short code has expired")
+	// ErrInvalidExpiry se devuelve cuando la expiración pedida no es válida.
+	ErrInvalidExpiry = errors.New("invalid expiration")
+	// ErrInvalidURL se devuelve cuando la URL no cumple la política de
+	// validación. El error envuelve un *validation.Error con el motivo.
+	ErrInvalidURL = errors.New("invalid URL")
+	// ErrInvalidRedirectType se devuelve cuando el tipo de redirección no es 301, 302, 307 ni 308.
+	ErrInvalidRedirectType = errors.New("invalid redirect type")
+	// ErrBlocked se devuelve cuando el destino está en la blocklist. El error
+	// concreto es un *BlockedError.
+	ErrBlocked = errors.New("destination is blocked")
+	// ErrNotOwner se devuelve al modificar o borrar con UpdateOwned o
+	// DeleteOwned un enlace de otro propietario.
+	ErrNotOwner = errors.New("link belongs to another owner")
+)
+
+// BlockedError indica que un destino está bloqueado y por qué regla.
+type BlockedError struct {
+	// Link es el enlace afectado. Al crear solo tiene LongURL.
+	Link Link
+	// Rule es la regla de la blocklist que bloqueó el destino.
+	Rule string
+}
+
+func (e *BlockedError) Error() string {
+	return ErrBlocked.Error()
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
+
+// Blocklist decide si un destino está bloqueado. Lo implementa *blocklist.Blocklist.
+type Blocklist interface {
+	Check(longURL string) blocklist.Verdict
+}
+
+// Clock abstrae la hora actual para poder inyectar un reloj falso en pruebas.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock es el reloj real basado en time.Now.
+var SystemClock Clock = systemClock{}
+
 type Shortener struct {
-	storage *Storage
+	storage Storage
+	clock   Clock
+	aliases AliasPolicy
+	// generator produce el candidato a código corto para cada intento.
+	generator CodeGenerator
+	// maxAttempts es el número de códigos que se prueban antes de rendirse.
+	maxAttempts int
+	// dedup reutiliza el código existente cuando se acorta una URL ya vista.
+	dedup bool
+	// dedupLocks serializa, por dominio y URL, la búsqueda y la creación con
+	// deduplicación activa.
+	dedupLocks [dedupStripes]sync.Mutex
+	// normalize configura la canonicalización de las URLs antes de guardarlas.
+	normalize util.NormalizeOptions
+	// policy decide qué URLs pueden acortarse.
+	policy validation.Policy
+	// blocklist es opcional; nil no bloquea ningún destino.
+	blocklist Blocklist
+	// logger es opcional; nil usa slog.Default() en cada llamada.
+	logger *slog.Logger
+
+	// Contadores del bucle de generación, para métricas
+	attempts   atomic.Uint64
+	collisions atomic.Uint64
+	exhausted  atomic.Uint64
+}
+
+// ShortenerStats son los contadores acumulados de la generación de códigos.
+type ShortenerStats struct {
+	// Attempts es el número de códigos generados que se intentaron guardar.
+	Attempts uint64
+	// Collisions es el número de intentos descartados por código en uso o reservado.
+	Collisions uint64
+	// Exhausted es el número de creaciones que agotaron los intentos.
+	Exhausted uint64
+}
+
+// Stats devuelve los contadores de generación de códigos.
+func (s *Shortener) Stats() ShortenerStats {
+	return ShortenerStats{
+		Attempts:   s.attempts.Load(),
+		Collisions: s.collisions.Load(),
+		Exhausted:  s.exhausted.Load(),
+	}
+}
+
+// ShortenerOption configura opciones opcionales del Shortener.
+type ShortenerOption func(*Shortener)
+
+// WithClock reemplaza el reloj usado para fechas de creación y expiración.
+func WithClock(clock Clock) ShortenerOption {
+	return func(s *Shortener) {
+		s.clock = clock
+	}
+}
+
+// WithGenerator reemplaza la estrategia de generación de códigos.
+func WithGenerator(generator CodeGenerator) ShortenerOption {
+	return func(s *Shortener) {
+		s.generator = generator
+	}
+}
+
+// WithMaxAttempts cambia el número de códigos que se prueban antes de
+// rendirse por colisiones.
+func WithMaxAttempts(attempts int) ShortenerOption {
+	return func(s *Shortener) {
+		s.maxAttempts = attempts
+	}
+}
+
+// WithLogger reemplaza el logger. Los métodos *Context registran con el
+// contexto recibido, así que el handler de slog puede añadir datos de la
+// petición como su identificador.
+func WithLogger(logger *slog.Logger) ShortenerOption {
+	return func(s *Shortener) {
+		s.logger = logger
+	}
+}
+
+func (s *Shortener) log() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+	return slog.Default()
+}
+
+// WithDedup activa la deduplicación: acortar una URL que ya tiene un enlace
+// permanente devuelve ese enlace en lugar de crear uno nuevo.
+func WithDedup(enabled bool) ShortenerOption {
+	return func(s *Shortener) {
+		s.dedup = enabled
+	}
+}
+
+// WithAliasPolicy reemplaza la política de validación de alias personalizados.
+func WithAliasPolicy(policy AliasPolicy) ShortenerOption {
+	return func(s *Shortener) {
+		s.aliases = policy
+	}
 }
 
-func NewShortener(storage *Storage) *Shortener {
+// WithNormalizeOptions reemplaza las opciones de canonicalización de URLs.
+func WithNormalizeOptions(opts util.NormalizeOptions) ShortenerOption {
+	return func(s *Shortener) {
+		s.normalize = opts
+	}
+}
+
+// WithValidationPolicy reemplaza la política de validación de URLs.
+func WithValidationPolicy(policy validation.Policy) ShortenerOption {
+	return func(s *Shortener) {
+		s.policy = policy
+	}
+}
+
+// WithBlocklist consulta blocklist al crear y al resolver enlaces.
+func WithBlocklist(blocklist Blocklist) ShortenerOption {
+	return func(s *Shortener) {
+		s.blocklist = blocklist
+	}
+}
+
+func NewShortener(storage Storage, opts ...ShortenerOption) *Shortener {
 	// Inicializar seed para random
 	rand.Seed(time.Now().UnixNano())
-	return &Shortener{storage: storage}
+	s := &Shortener{
+		storage:     storage,
+		clock:       SystemClock,
+		aliases:     DefaultAliasPolicy(),
+		generator:   NewHashGenerator(DefaultShortCodeLength),
+		maxAttempts: DefaultMaxAttempts,
+		normalize:   util.DefaultNormalizeOptions(),
+		policy:      validation.DefaultPolicy(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateOptions son los parámetros opcionales al crear un enlace.
+type CreateOptions struct {
+	// ExpiresAt es el instante de expiración. Incompatible con TTL.
+	ExpiresAt time.Time
+	// TTL es la vida del enlace a partir de ahora. Incompatible con ExpiresAt.
+	TTL time.Duration
+	// Alias es un código corto elegido por el usuario. Vacío genera uno aleatorio.
+	Alias string
+	// RedirectType es el código HTTP de la redirección (301, 302, 307 o 308).
+	// Cero usa el valor por defecto del servidor.
+	RedirectType int
+	// Domain es el dominio corto en el que se crea el código. Vacío es el
+	// dominio por defecto.
+	Domain string
+	// Owner es el propietario del enlace.
+	Owner string
 }
 
 func (s *Shortener) CreateShortURL(longURL string) (string, error) {
-	// Intentar generar código único hasta MAX_ATTEMPTS veces
-	for attempts := 0; attempts < MAX_ATTEMPTS; attempts++ {
-		shortCode := s.generateShortCode(longURL, attempts)
-
-		// Verificar si ya existe
-		if !s.storage.Exists(shortCode) {
-			s.storage.Store(shortCode, longURL)
-			return shortCode, nil
+	link, _, err := s.Create(longURL, CreateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return link.ShortCode, nil
+}
+
+// Create guarda un enlace para longURL con el alias pedido o, si no hay
+// alias, con un código corto único generado. La URL se guarda en su forma
+// canónica. El booleano indica si el enlace es nuevo (false cuando la
+// deduplicación reutilizó uno existente).
+func (s *Shortener) Create(longURL string, opts CreateOptions) (Link, bool, error) {
+	return s.CreateContext(context.Background(), longURL, opts)
+}
+
+// CreateContext es como Create y registra el resultado con ctx.
+func (s *Shortener) CreateContext(ctx context.Context, longURL string, opts CreateOptions) (Link, bool, error) {
+	link, created, err := s.create(ctx, longURL, opts)
+	var blocked *BlockedError
+	switch {
+	case errors.As(err, &blocked):
+		s.log().InfoContext(ctx, "destino bloqueado al crear", "url", blocked.Link.LongURL, "rule", blocked.Rule)
+	case err == nil && created:
+		s.log().DebugContext(ctx, "enlace creado", "key", link.Key(), "owner", link.Owner)
+	case err == nil:
+		s.log().DebugContext(ctx, "enlace reutilizado", "key", link.Key(), "owner", link.Owner)
+	}
+	return link, created, err
+}
+
+func (s *Shortener) create(ctx context.Context, longURL string, opts CreateOptions) (Link, bool, error) {
+	now := s.clock.Now()
+
+	// Canonicalizar antes de deduplicar para que URLs equivalentes coincidan
+	longURL, err := s.prepareURL(longURL)
+	if err != nil {
+		return Link{}, false, err
+	}
+
+	expiresAt, err := resolveExpiry(now, opts)
+	if err != nil {
+		return Link{}, false, err
+	}
+	if opts.RedirectType != 0 && !ValidRedirectType(opts.RedirectType) {
+		return Link{}, false, fmt.Errorf("%w: %d", ErrInvalidRedirectType, opts.RedirectType)
+	}
+
+	link := Link{
+		Domain:       opts.Domain,
+		Owner:        opts.Owner,
+		LongURL:      longURL,
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
+		RedirectType: opts.RedirectType,
+	}
+
+	if opts.Alias != "" {
+		link, err := s.createAlias(link, opts.Alias)
+		return link, err == nil, err
+	}
+
+	// Solo se reutilizan enlaces permanentes del mismo dominio, propietario y
+	// tipo de redirección, y solo si la petición tampoco pide expiración. Las
+	// peticiones simultáneas de la misma URL se serializan para que la
+	// segunda encuentre el enlace de la primera.
+	if s.dedup && expiresAt.IsZero() {
+		lock := &s.dedupLocks[fnv32a(opts.Domain, longURL)%dedupStripes]
+		lock.Lock()
+		defer lock.Unlock()
+		if existing, found := s.storage.LookupURL(opts.Domain, longURL); found &&
+			existing.RedirectType == opts.RedirectType && existing.Owner == opts.Owner {
+			return existing, false, nil
 		}
 	}
 
-	return "", fmt.Errorf("failed to generate unique short code after %d attempts", MAX_ATTEMPTS)
+	link, err = s.createGenerated(ctx, link)
+	return link, err == nil, err
+}
+
+// prepareURL valida longURL, la canonicaliza y comprueba que su destino no
+// esté bloqueado. Devuelve la forma canónica.
+func (s *Shortener) prepareURL(longURL string) (string, error) {
+	if err := s.policy.Validate(longURL); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	canonical, err := util.NormalizeURL(longURL, s.normalize)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	// Se consulta la forma canónica para que mayúsculas o puertos no eludan las reglas
+	if err := s.checkBlocked(Link{LongURL: canonical}); err != nil {
+		return "", err
+	}
+	return canonical, nil
+}
+
+// createGenerated genera un código único y guarda link con él.
+func (s *Shortener) createGenerated(ctx context.Context, link Link) (Link, error) {
+	// Intentar generar código único hasta maxAttempts veces
+	for attempts := 0; attempts < s.maxAttempts; attempts++ {
+		shortCode := s.generateShortCode(link.LongURL, attempts)
+		s.attempts.Add(1)
+
+		// Los códigos reservados se tratan como colisiones
+		if s.aliases.IsReserved(shortCode) {
+			s.collisions.Add(1)
+			continue
+		}
+
+		// Comprobar y guardar en una sola operación atómica: si otra
+		// petición concurrente reclamó el mismo código, se reintenta
+		link.ShortCode = shortCode
+		stored, err := s.storage.StoreIfAbsent(link)
+		if err != nil {
+			return Link{}, fmt.Errorf("failed to store short code: %w", err)
+		}
+		if stored {
+			return link, nil
+		}
+		s.collisions.Add(1)
+		s.log().DebugContext(ctx, "colisión de código", "code", shortCode, "attempt", attempts+1)
+	}
+
+	s.exhausted.Add(1)
+	s.log().WarnContext(ctx, "intentos de generación agotados", "attempts", s.maxAttempts, "url", link.LongURL)
+	return Link{}, fmt.Errorf("failed to generate unique short code after %d attempts", s.maxAttempts)
+}
+
+// createAlias valida el alias y reclama link con él de forma atómica.
+func (s *Shortener) createAlias(link Link, alias string) (Link, error) {
+	if err := s.aliases.Validate(alias); err != nil {
+		return Link{}, err
+	}
+
+	link.ShortCode = alias
+	stored, err := s.storage.StoreIfAbsent(link)
+	if err != nil {
+		return Link{}, fmt.Errorf("failed to store alias: %w", err)
+	}
+	if !stored {
+		return Link{}, fmt.Errorf("%w: %q", ErrAliasTaken, alias)
+	}
+	return link, nil
+}
+
+// ValidRedirectType indica si code es un tipo de redirección admitido.
+func ValidRedirectType(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// resolveExpiry calcula el instante de expiración a partir de opts.
+func resolveExpiry(now time.Time, opts CreateOptions) (time.Time, error) {
+	switch {
+	case !opts.ExpiresAt.IsZero() && opts.TTL != 0:
+		return time.Time{}, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", ErrInvalidExpiry)
+	case opts.TTL < 0:
+		return time.Time{}, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiry)
+	case opts.TTL > 0:
+		return now.Add(opts.TTL), nil
+	case !opts.ExpiresAt.IsZero() && !opts.ExpiresAt.After(now):
+		return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+	default:
+		return opts.ExpiresAt, nil
+	}
 }
 
 func (s *Shortener) GetLongURL(shortCode string) (string, bool) {
-	return s.storage.Get(shortCode)
+	link, err := s.Resolve(shortCode)
+	if err != nil {
+		return "", false
+	}
+	return link.LongURL, true
 }
 
-func (s *Shortener) generateShortCode(longURL string, attempt int) string {
-	// Combinar URL + timestamp + attempt para evitar colisiones
-	// Solo usando librerías estándar
-	input := fmt.Sprintf("%s%d%d", longURL, time.Now().UnixNano(), attempt)
+// LinkUpdate son los cambios a aplicar a un enlace. Los campos vacíos o nil
+// no se modifican.
+type LinkUpdate struct {
+	// LongURL es el nuevo destino. Pasa por la misma validación y
+	// canonicalización que al crear.
+	LongURL string
+	// RedirectType es el nuevo tipo de redirección (0 = el del servidor).
+	RedirectType *int
+}
 
-	// Hash SHA1 (librería estándar)
-	hash := sha1.Sum([]byte(input))
+// Update aplica update al enlace con la clave key (ver LinkKey).
+func (s *Shortener) Update(key string, update LinkUpdate) (Link, error) {
+	return s.update(key, update, "", false)
+}
 
-	// Convertir a string hexadecimal y tomar los primeros SHORT_CODE_LENGTH caracteres
-	shortCode := fmt.Sprintf("%x", hash)[:SHORT_CODE_LENGTH]
+// UpdateOwned es como Update, pero devuelve ErrNotOwner si el enlace no es de
+// owner. La comprobación se hace bajo el mismo bloqueo que la escritura, así
+// que no afecta a un enlace que otro propietario cree con la misma clave.
+func (s *Shortener) UpdateOwned(owner, key string, update LinkUpdate) (Link, error) {
+	return s.update(key, update, owner, true)
+}
 
-	// Si es un reintento, agregar aleatoriedad extra
-	if attempt > 0 {
-		// Agregar 2 caracteres aleatorios al final
-		randomSuffix := rand.Intn(256) // 0-255
-		shortCode = fmt.Sprintf("%s%02x", shortCode[:SHORT_CODE_LENGTH-2], randomSuffix)
+func (s *Shortener) update(key string, update LinkUpdate, owner string, checkOwner bool) (Link, error) {
+	var longURL string
+	if update.LongURL != "" {
+		var err error
+		if longURL, err = s.prepareURL(update.LongURL); err != nil {
+			return Link{}, err
+		}
 	}
+	if update.RedirectType != nil && *update.RedirectType != 0 && !ValidRedirectType(*update.RedirectType) {
+		return Link{}, fmt.Errorf("%w: %d", ErrInvalidRedirectType, *update.RedirectType)
+	}
+
+	return s.storage.Update(key, func(link Link) (Link, error) {
+		if checkOwner && link.Owner != owner {
+			return Link{}, ErrNotOwner
+		}
+		if longURL != "" {
+			link.LongURL = longURL
+		}
+		if update.RedirectType != nil {
+			link.RedirectType = *update.RedirectType
+		}
+		return link, nil
+	})
+}
+
+// Delete elimina el enlace con la clave key. Devuelve ErrNotFound si no existe.
+func (s *Shortener) Delete(key string) error {
+	return s.storage.Delete(key)
+}
+
+// DeleteOwned es como Delete, pero devuelve ErrNotOwner sin borrar nada si el
+// enlace no es de owner.
+func (s *Shortener) DeleteOwned(owner, key string) error {
+	return s.storage.DeleteIf(key, func(link Link) error {
+		if link.Owner != owner {
+			return ErrNotOwner
+		}
+		return nil
+	})
+}
+
+// List devuelve hasta limit enlaces con clave mayor que after, en orden.
+func (s *Shortener) List(after string, limit int) []Link {
+	return s.storage.ListPage(after, limit)
+}
 
-	return shortCode
+// ListOwned es como List pero solo devuelve los enlaces de owner.
+func (s *Shortener) ListOwned(owner, after string, limit int) []Link {
+	return s.storage.ListOwnedPage(owner, after, limit)
+}
+
+// Lookup devuelve el enlace guardado con la clave key, aunque haya expirado
+// o su destino esté bloqueado.
+func (s *Shortener) Lookup(key string) (Link, bool) {
+	return s.storage.Get(key)
+}
+
+// Resolve devuelve el enlace con la clave key (ver LinkKey). Devuelve
+// ErrNotFound si no existe, ErrExpired si ya expiró pero aún no fue purgado y
+// un *BlockedError si su destino quedó bloqueado después de crearlo.
+func (s *Shortener) Resolve(key string) (Link, error) {
+	return s.ResolveContext(context.Background(), key)
+}
+
+// ResolveContext es como Resolve y registra con ctx las redirecciones bloqueadas.
+func (s *Shortener) ResolveContext(ctx context.Context, key string) (Link, error) {
+	link, exists := s.storage.Get(key)
+	if !exists {
+		return Link{}, ErrNotFound
+	}
+	if link.Expired(s.clock.Now()) {
+		return Link{}, ErrExpired
+	}
+	if err := s.checkBlocked(link); err != nil {
+		var blocked *BlockedError
+		if errors.As(err, &blocked) {
+			s.log().WarnContext(ctx, "redirección bloqueada", "key", key, "url", link.LongURL, "rule", blocked.Rule)
+		}
+		return Link{}, err
+	}
+	return link, nil
+}
+
+// checkBlocked devuelve un *BlockedError si el destino de link está bloqueado.
+func (s *Shortener) checkBlocked(link Link) error {
+	if s.blocklist == nil {
+		return nil
+	}
+	if verdict := s.blocklist.Check(link.LongURL); verdict.Blocked {
+		return &BlockedError{Link: link, Rule: verdict.Rule}
+	}
+	return nil
+}
+
+func (s *Shortener) generateShortCode(longURL string, attempt int) string {
+	return s.generator.Generate(longURL, attempt)
 }
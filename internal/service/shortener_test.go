@@ -1,10 +1,21 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"net/http"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/blocklist"
+	"github.com/jackparradev/url-inteligente/internal/logging"
+	"github.com/jackparradev/url-inteligente/internal/util"
+	"github.com/jackparradev/url-inteligente/internal/validation"
 )
 
 func TestShortener_CreateShortURL(t *testing.T) {
@@ -18,8 +29,8 @@ func TestShortener_CreateShortURL(t *testing.T) {
 		t.Errorf("Expected no error, got %v", err)
 	}
 	
-	if len(shortCode) != SHORT_CODE_LENGTH {
-		t.Errorf("Expected short code length %d, got %d", SHORT_CODE_LENGTH, len(shortCode))
+	if len(shortCode) != DefaultShortCodeLength {
+		t.Errorf("Expected short code length %d, got %d", DefaultShortCodeLength, len(shortCode))
 	}
 	
 	// Verificar que se puede recuperar
@@ -55,8 +66,8 @@ func TestShortener_GenerateShortCode(t *testing.T) {
 		code := shortener.generateShortCode(longURL, i)
 		
 		// Verificar longitud
-		if len(code) != SHORT_CODE_LENGTH {
-			t.Errorf("Expected length %d, got %d", SHORT_CODE_LENGTH, len(code))
+		if len(code) != DefaultShortCodeLength {
+			t.Errorf("Expected length %d, got %d", DefaultShortCodeLength, len(code))
 		}
 		
 		// Verificar que solo contiene caracteres hexadecimales
@@ -148,3 +159,451 @@ func TestShortener_CollisionHandling(t *testing.T) {
 		t.Error("URLs do not match original values")
 	}
 }
+
+// fakeClock es un reloj controlable para pruebas de expiración.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestShortener_CreateWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	shortener := NewShortener(NewStorage(), WithClock(clock))
+
+	link, _, err := shortener.Create("https://www.google.com", CreateOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !link.CreatedAt.Equal(clock.Now()) {
+		t.Errorf("Expected created_at %v, got %v", clock.Now(), link.CreatedAt)
+	}
+	if !link.ExpiresAt.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("Expected expires_at %v, got %v", clock.Now().Add(time.Hour), link.ExpiresAt)
+	}
+
+	if _, err := shortener.Resolve(link.ShortCode); err != nil {
+		t.Errorf("Expected link to resolve before expiry, got %v", err)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := shortener.Resolve(link.ShortCode); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+	if _, exists := shortener.GetLongURL(link.ShortCode); exists {
+		t.Error("Expected GetLongURL to hide expired links")
+	}
+}
+
+func TestShortener_CreateWithExpiresAt(t *testing.T) {
+	clock := newFakeClock()
+	shortener := NewShortener(NewStorage(), WithClock(clock))
+
+	expiresAt := clock.Now().Add(24 * time.Hour)
+	link, _, err := shortener.Create("https://www.google.com", CreateOptions{ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !link.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected expires_at %v, got %v", expiresAt, link.ExpiresAt)
+	}
+}
+
+func TestShortener_CreateInvalidExpiry(t *testing.T) {
+	clock := newFakeClock()
+	shortener := NewShortener(NewStorage(), WithClock(clock))
+
+	invalid := []CreateOptions{
+		{ExpiresAt: clock.Now().Add(-time.Minute)},
+		{ExpiresAt: clock.Now()},
+		{TTL: -time.Second},
+		{ExpiresAt: clock.Now().Add(time.Hour), TTL: time.Hour},
+	}
+	for _, opts := range invalid {
+		if _, _, err := shortener.Create("https://www.google.com", opts); !errors.Is(err, ErrInvalidExpiry) {
+			t.Errorf("Expected ErrInvalidExpiry for %+v, got %v", opts, err)
+		}
+	}
+}
+
+func TestShortener_ResolveNotFound(t *testing.T) {
+	shortener := NewShortener(NewStorage())
+
+	if _, err := shortener.Resolve("nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestShortener_ConcurrentCollisionsNeverLoseMappings(t *testing.T) {
+	storage := NewStorage()
+
+	// Generador con un espacio de códigos diminuto para forzar colisiones
+	// constantes entre goroutines
+	var rngMu sync.Mutex
+	rng := rand.New(rand.NewSource(1))
+	shortener := NewShortener(storage, WithGenerator(CodeGeneratorFunc(func(longURL string, attempt int) string {
+		rngMu.Lock()
+		defer rngMu.Unlock()
+		return fmt.Sprintf("c%02d", rng.Intn(64))
+	})))
+
+	type result struct {
+		shortCode string
+		longURL   string
+	}
+
+	var wg sync.WaitGroup
+	numGoroutines := 32
+	perGoroutine := 20
+	results := make(chan result, numGoroutines*perGoroutine)
+
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				longURL := fmt.Sprintf("https://test%d-%d.com", id, j)
+				shortCode, err := shortener.CreateShortURL(longURL)
+				if err != nil {
+					// Agotar los intentos es aceptable; perder un enlace no
+					continue
+				}
+				results <- result{shortCode: shortCode, longURL: longURL}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	created := 0
+	codes := make(map[string]bool)
+	for r := range results {
+		created++
+		if codes[r.shortCode] {
+			t.Errorf("Short code %s handed out twice", r.shortCode)
+		}
+		codes[r.shortCode] = true
+
+		link, exists := storage.Get(r.shortCode)
+		if !exists || link.LongURL != r.longURL {
+			t.Errorf("Mapping for %s lost: expected %s, got %q", r.shortCode, r.longURL, link.LongURL)
+		}
+	}
+
+	if created == 0 {
+		t.Fatal("Expected some creations to succeed")
+	}
+	if storage.Count() != created {
+		t.Errorf("Expected %d stored links, got %d", created, storage.Count())
+	}
+}
+
+func TestShortener_CreateStoresCanonicalURL(t *testing.T) {
+	storage := NewStorage()
+	shortener := NewShortener(storage)
+
+	link, _, err := shortener.Create("HTTP://Example.COM:80/a/./b?utm_campaign=x&z=1&a=2", CreateOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := "http://example.com/a/b?a=2&z=1"
+	if link.LongURL != expected {
+		t.Errorf("Expected canonical URL %s, got %s", expected, link.LongURL)
+	}
+	if stored, _ := storage.Get(link.ShortCode); stored.LongURL != expected {
+		t.Errorf("Expected stored URL %s, got %s", expected, stored.LongURL)
+	}
+
+	// Las opciones de normalización son configurables
+	custom := NewShortener(NewStorage(), WithNormalizeOptions(util.NormalizeOptions{TrackingParams: []string{"ref"}}))
+	link, _, _ = custom.Create("https://example.com/?utm_source=x&ref=y", CreateOptions{})
+	if link.LongURL != "https://example.com?utm_source=x" {
+		t.Errorf("Expected custom tracking params to be stripped, got %s", link.LongURL)
+	}
+}
+
+func TestShortener_CreateInvalidURL(t *testing.T) {
+	shortener := NewShortener(NewStorage())
+
+	if _, _, err := shortener.Create("http://[::1", CreateOptions{}); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("Expected ErrInvalidURL, got %v", err)
+	}
+
+	// El motivo del rechazo viaja en el error y la política es configurable
+	_, _, err := shortener.Create("https://10.0.0.1", CreateOptions{})
+	var invalid *validation.Error
+	if !errors.As(err, &invalid) || invalid.Reason != validation.ReasonIPLiteral {
+		t.Errorf("Expected ip_literal reason, got %v", err)
+	}
+
+	policy := validation.DefaultPolicy()
+	policy.AllowIPLiterals = true
+	permissive := NewShortener(NewStorage(), WithValidationPolicy(policy))
+	if _, _, err := permissive.Create("https://10.0.0.1", CreateOptions{}); err != nil {
+		t.Errorf("Expected IP literal to be accepted by custom policy, got %v", err)
+	}
+}
+
+// mutableBlocklist permite cambiar las reglas durante una prueba.
+type mutableBlocklist struct {
+	rules *blocklist.Rules
+}
+
+func (b *mutableBlocklist) Check(longURL string) blocklist.Verdict {
+	return b.rules.Check(longURL)
+}
+
+func mustParseRules(t *testing.T, text string) *blocklist.Rules {
+	t.Helper()
+	rules, err := blocklist.Parse(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Expected no error parsing rules, got %v", err)
+	}
+	return rules
+}
+
+func TestShortener_CreateBlocked(t *testing.T) {
+	storage := NewStorage()
+	shortener := NewShortener(storage, WithBlocklist(mustParseRules(t, "*.evil.com\nre:/login")))
+
+	for _, url := range []string{"https://evil.com", "https://WWW.Evil.com:443/x", "https://bank.com/login"} {
+		_, _, err := shortener.Create(url, CreateOptions{})
+		var blocked *BlockedError
+		if !errors.As(err, &blocked) || !errors.Is(err, ErrBlocked) {
+			t.Errorf("Expected %s to be blocked, got %v", url, err)
+		}
+	}
+	if storage.Count() != 0 {
+		t.Errorf("Expected no links stored, got %d", storage.Count())
+	}
+
+	if _, _, err := shortener.Create("https://good.com", CreateOptions{}); err != nil {
+		t.Errorf("Expected good.com to be allowed, got %v", err)
+	}
+}
+
+func TestShortener_ResolveBlockedAfterCreation(t *testing.T) {
+	list := &mutableBlocklist{rules: mustParseRules(t, "")}
+	shortener := NewShortener(NewStorage(), WithBlocklist(list))
+
+	code, err := shortener.CreateShortURL("https://soon-evil.com/page")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	list.rules = mustParseRules(t, "soon-evil.com")
+
+	_, err = shortener.Resolve(code)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Expected BlockedError, got %v", err)
+	}
+	if blocked.Link.LongURL != "https://soon-evil.com/page" || blocked.Rule != "soon-evil.com" {
+		t.Errorf("Unexpected blocked error %+v", blocked)
+	}
+	if _, found := shortener.GetLongURL(code); found {
+		t.Error("Expected GetLongURL to hide blocked destinations")
+	}
+}
+
+func TestShortener_Update(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithBlocklist(mustParseRules(t, "evil.com")))
+
+	code, _ := shortener.CreateShortURL("https://old.com")
+
+	link, err := shortener.Update(code, LinkUpdate{LongURL: "HTTPS://New.com:443/?utm_source=x"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if link.LongURL != "https://new.com" {
+		t.Errorf("Expected canonical target https://new.com, got %s", link.LongURL)
+	}
+	if longURL, _ := shortener.GetLongURL(code); longURL != "https://new.com" {
+		t.Errorf("Expected redirect to new target, got %s", longURL)
+	}
+
+	// Cambiar solo el tipo de redirección conserva el destino
+	redirectType := http.StatusTemporaryRedirect
+	link, err = shortener.Update(code, LinkUpdate{RedirectType: &redirectType})
+	if err != nil || link.RedirectType != redirectType || link.LongURL != "https://new.com" {
+		t.Errorf("Expected only redirect type to change, got %+v (err=%v)", link, err)
+	}
+
+	invalidType := 303
+	if _, err := shortener.Update(code, LinkUpdate{RedirectType: &invalidType}); !errors.Is(err, ErrInvalidRedirectType) {
+		t.Errorf("Expected ErrInvalidRedirectType, got %v", err)
+	}
+	if _, err := shortener.Update(code, LinkUpdate{LongURL: "ftp://new.com"}); !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("Expected ErrInvalidURL, got %v", err)
+	}
+	if _, err := shortener.Update(code, LinkUpdate{LongURL: "https://evil.com"}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected ErrBlocked, got %v", err)
+	}
+	if _, err := shortener.Update("missing", LinkUpdate{LongURL: "https://new.com"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestShortener_UpdateOwnedAndDeleteOwned(t *testing.T) {
+	shortener := NewShortener(NewStorage())
+	link, _, _ := shortener.Create("https://old.com", CreateOptions{Alias: "promo", Owner: "alice"})
+	key := link.Key()
+
+	if _, err := shortener.UpdateOwned("bob", key, LinkUpdate{LongURL: "https://bob.com"}); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner, got %v", err)
+	}
+	if err := shortener.DeleteOwned("bob", key); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner, got %v", err)
+	}
+	if got, _ := shortener.Lookup(key); got.LongURL != "https://old.com" {
+		t.Errorf("Expected link untouched by another owner, got %s", got.LongURL)
+	}
+
+	if _, err := shortener.UpdateOwned("alice", key, LinkUpdate{LongURL: "https://new.com"}); err != nil {
+		t.Errorf("Expected owner to update, got %v", err)
+	}
+	if err := shortener.DeleteOwned("alice", key); err != nil {
+		t.Errorf("Expected owner to delete, got %v", err)
+	}
+
+	// El mismo código recreado por otro ya no es de alice
+	shortener.Create("https://bob.com", CreateOptions{Alias: "promo", Owner: "bob"})
+	if err := shortener.DeleteOwned("alice", key); !errors.Is(err, ErrNotOwner) {
+		t.Errorf("Expected ErrNotOwner on recreated code, got %v", err)
+	}
+}
+
+func TestShortener_CreateRedirectType(t *testing.T) {
+	shortener := NewShortener(NewStorage(), WithDedup(true))
+
+	link, _, err := shortener.Create("https://www.google.com", CreateOptions{RedirectType: http.StatusPermanentRedirect})
+	if err != nil || link.RedirectType != http.StatusPermanentRedirect {
+		t.Errorf("Expected redirect type 308, got %d (err=%v)", link.RedirectType, err)
+	}
+
+	again, created, _ := shortener.Create("https://www.google.com", CreateOptions{RedirectType: http.StatusPermanentRedirect})
+	if created || again.ShortCode != link.ShortCode {
+		t.Errorf("Expected to reuse %s, got %s (created=%v)", link.ShortCode, again.ShortCode, created)
+	}
+
+	// La deduplicación no mezcla enlaces con distinto tipo de redirección
+	other, created, _ := shortener.Create("https://www.google.com", CreateOptions{})
+	if !created || other.ShortCode == link.ShortCode {
+		t.Error("Expected a new link for a different redirect type")
+	}
+
+	for _, code := range []int{200, 303, 404} {
+		if _, _, err := shortener.Create("https://www.github.com", CreateOptions{RedirectType: code}); !errors.Is(err, ErrInvalidRedirectType) {
+			t.Errorf("Expected ErrInvalidRedirectType for %d, got %v", code, err)
+		}
+	}
+}
+
+func TestShortener_MaxAttempts(t *testing.T) {
+	calls := 0
+	generator := CodeGeneratorFunc(func(longURL string, attempt int) string {
+		calls++
+		return "taken"
+	})
+	storage := NewStorage()
+	storage.Store(Link{ShortCode: "taken", LongURL: "https://www.google.com"})
+	shortener := NewShortener(storage, WithGenerator(generator), WithMaxAttempts(3))
+
+	if _, _, err := shortener.Create("https://www.github.com", CreateOptions{}); err == nil {
+		t.Error("Expected error when every attempt collides")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestShortener_ListOwned(t *testing.T) {
+	storage := NewStorage()
+	for i := 0; i < 10; i++ {
+		owner := "alice"
+		if i%3 == 0 {
+			owner = "bob"
+		}
+		storage.Store(Link{ShortCode: fmt.Sprintf("code%d", i), LongURL: "https://www.google.com", Owner: owner})
+	}
+	shortener := NewShortener(storage)
+
+	// bob tiene code0, code3, code6 y code9, repartidos entre páginas del storage
+	links := shortener.ListOwned("bob", "", 3)
+	if len(links) != 3 || links[0].ShortCode != "code0" || links[2].ShortCode != "code6" {
+		t.Fatalf("Expected code0, code3, code6, got %+v", links)
+	}
+	links = shortener.ListOwned("bob", links[2].Key(), 3)
+	if len(links) != 1 || links[0].ShortCode != "code9" {
+		t.Errorf("Expected code9, got %+v", links)
+	}
+	if links := shortener.ListOwned("carol", "", 3); len(links) != 0 {
+		t.Errorf("Expected no links for carol, got %+v", links)
+	}
+}
+
+func TestShortener_Stats(t *testing.T) {
+	codes := []string{"taken", "shorten", "free01", "taken", "taken"}
+	generator := CodeGeneratorFunc(func(longURL string, attempt int) string {
+		code := codes[0]
+		codes = codes[1:]
+		return code
+	})
+	storage := NewStorage()
+	storage.Store(Link{ShortCode: "taken", LongURL: "https://www.google.com"})
+	shortener := NewShortener(storage, WithGenerator(generator), WithMaxAttempts(2))
+
+	// "taken" colisiona, "shorten" está reservado: se agotan los dos intentos
+	if _, _, err := shortener.Create("https://www.github.com", CreateOptions{}); err == nil {
+		t.Fatal("Expected error when every attempt collides")
+	}
+	// "free01" entra al primer intento
+	if _, _, err := shortener.Create("https://www.github.com", CreateOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stats := shortener.Stats()
+	if stats.Attempts != 3 || stats.Collisions != 2 || stats.Exhausted != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestShortener_CreateContext_LogsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := logging.NewLogger(&buf, "json", "debug")
+	shortener := NewShortener(NewStorage(), WithLogger(logger), WithBlocklist(mustParseRules(t, "evil.com")))
+	ctx := logging.NewContext(context.Background(), "req-7")
+
+	link, _, err := shortener.CreateContext(ctx, "https://www.example.com", CreateOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(buf.String(), `"msg":"enlace creado"`) || !strings.Contains(buf.String(), `"key":"`+link.ShortCode+`"`) {
+		t.Errorf("Expected creation to be logged, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"request_id":"req-7"`) {
+		t.Errorf("Expected request id from context in service logs, got %q", buf.String())
+	}
+
+	buf.Reset()
+	shortener.CreateContext(ctx, "https://evil.com/x", CreateOptions{})
+	if !strings.Contains(buf.String(), `"msg":"destino bloqueado al crear"`) || !strings.Contains(buf.String(), `"request_id":"req-7"`) {
+		t.Errorf("Expected blocked creation to be logged with request id, got %q", buf.String())
+	}
+}
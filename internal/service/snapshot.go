@@ -0,0 +1,211 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Nombres de archivo dentro del directorio de datos:
+//
+//	wal-<seq>.log       segmento del log de escritura
+//	snapshot-<seq>.snap estado completo tras aplicar los segmentos <= seq
+//
+// Formato del snapshot: [magic 8 bytes][crc32c uint32 LE][longitud uint64 LE][payload JSON]
+const (
+	segmentPrefix  = "wal-"
+	segmentSuffix  = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".snap"
+
+	// legacyWALFileName es el log único usado antes de dividirlo en segmentos.
+	legacyWALFileName = "wal.log"
+
+	snapshotMagic      = "URLSNAP1"
+	snapshotHeaderSize = len(snapshotMagic) + 4 + 8
+)
+
+var errSnapshotCorrupt = errors.New("corrupt snapshot")
+
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", segmentPrefix, seq, segmentSuffix))
+}
+
+func snapshotPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", snapshotPrefix, seq, snapshotSuffix))
+}
+
+// listSeqs devuelve, en orden ascendente, los números de secuencia de los
+// archivos de dir con el prefijo y sufijo indicados.
+func listSeqs(dir, prefix, suffix string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// listSegments devuelve los segmentos del log en orden. Un log único de una
+// versión anterior se renombra como primer segmento.
+func listSegments(dir string) ([]uint64, error) {
+	segments, err := listSeqs(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return nil, err
+	}
+	legacy := filepath.Join(dir, legacyWALFileName)
+	if _, err := os.Stat(legacy); err == nil && len(segments) == 0 {
+		if err := os.Rename(legacy, segmentPath(dir, 1)); err != nil {
+			return nil, fmt.Errorf("migrating %s: %w", legacyWALFileName, err)
+		}
+		segments = []uint64{1}
+	}
+	return segments, nil
+}
+
+// writeSnapshot escribe de forma atómica el snapshot seq: primero a un
+// archivo temporal que se sincroniza y luego se renombra.
+func writeSnapshot(dir string, seq uint64, links []Link) error {
+	payload, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, snapshotHeaderSize)
+	copy(header, snapshotMagic)
+	binary.LittleEndian.PutUint32(header[len(snapshotMagic):], crc32.Checksum(payload, walCRCTable))
+	binary.LittleEndian.PutUint64(header[len(snapshotMagic)+4:], uint64(len(payload)))
+
+	path := snapshotPath(dir, seq)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(header); err == nil {
+		_, err = tmp.Write(payload)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming snapshot: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// readSnapshot carga y verifica el snapshot en path.
+func readSnapshot(path string) ([]Link, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < snapshotHeaderSize || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errSnapshotCorrupt
+	}
+	checksum := binary.LittleEndian.Uint32(data[len(snapshotMagic):])
+	length := binary.LittleEndian.Uint64(data[len(snapshotMagic)+4:])
+	payload := data[snapshotHeaderSize:]
+	if uint64(len(payload)) != length || crc32.Checksum(payload, walCRCTable) != checksum {
+		return nil, errSnapshotCorrupt
+	}
+
+	var links []Link
+	if err := json.Unmarshal(payload, &links); err != nil {
+		return nil, errSnapshotCorrupt
+	}
+	return links, nil
+}
+
+// loadLatestSnapshot aplica el snapshot válido más reciente y devuelve su
+// número de secuencia (0 si no hay ninguno). Los snapshots corruptos se
+// ignoran y se prueba con el anterior.
+func loadLatestSnapshot(dir string, apply func(Link)) (uint64, error) {
+	seqs, err := listSeqs(dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return 0, err
+	}
+	for i := len(seqs) - 1; i >= 0; i-- {
+		links, err := readSnapshot(snapshotPath(dir, seqs[i]))
+		if err != nil {
+			log.Printf("Storage: snapshot %d ignorado: %v", seqs[i], err)
+			continue
+		}
+		for _, link := range links {
+			apply(link)
+		}
+		return seqs[i], nil
+	}
+	return 0, nil
+}
+
+// compact elimina los segmentos cubiertos por el snapshot seq y los
+// snapshots anteriores.
+func compact(dir string, seq uint64) error {
+	segments, err := listSeqs(dir, segmentPrefix, segmentSuffix)
+	if err != nil {
+		return err
+	}
+	for _, s := range segments {
+		if s <= seq {
+			if err := os.Remove(segmentPath(dir, s)); err != nil {
+				return fmt.Errorf("compacting write-ahead log: %w", err)
+			}
+		}
+	}
+
+	snapshots, err := listSeqs(dir, snapshotPrefix, snapshotSuffix)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		if s < seq {
+			if err := os.Remove(snapshotPath(dir, s)); err != nil {
+				return fmt.Errorf("removing old snapshot: %w", err)
+			}
+		}
+	}
+	return syncDir(dir)
+}
+
+// syncDir sincroniza el directorio para que creaciones, renombres y borrados
+// sobrevivan a una caída.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing directory: %w", err)
+	}
+	return nil
+}
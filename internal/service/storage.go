@@ -1,36 +1,159 @@
 package service
 
 import (
-	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
-type Storage struct {
-	mu   sync.RWMutex
-	urls map[string]string
+// ErrNotFound se devuelve cuando el código corto no existe en el almacenamiento.
+var ErrNotFound = errors.New("short code not found")
+
+// ErrClosed se devuelve al escribir en un almacenamiento ya cerrado.
+var ErrClosed = errors.New("storage is closed")
+
+// Link representa un mapeo entre un código corto y la URL original.
+// Las etiquetas JSON definen su forma persistida en el log y los snapshots.
+type Link struct {
+	ShortCode string `json:"code"`
+	// Domain es el host del dominio corto al que pertenece el enlace. Vacío es
+	// el dominio por defecto. Cada dominio tiene su propio espacio de códigos.
+	Domain    string    `json:"domain,omitempty"`
+	LongURL   string    `json:"url"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// ExpiresAt es el instante a partir del cual el enlace deja de ser
+	// válido. El valor cero significa que nunca expira.
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	// RedirectType es el código HTTP de la redirección (301, 302, 307 o 308).
+	// Cero usa el valor por defecto del servidor.
+	RedirectType int `json:"redirect_type,omitzero"`
+	// Owner es el propietario de la clave de API que creó el enlace. Vacío
+	// si se creó sin autenticación.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Key devuelve la clave con la que se guarda el enlace: el código en el
+// dominio por defecto y "dominio/código" en los demás.
+func (l Link) Key() string {
+	return LinkKey(l.Domain, l.ShortCode)
 }
 
-func NewStorage() *Storage {
-	return &Storage{
-		urls: make(map[string]string),
+// LinkKey construye la clave de almacenamiento de un código en un dominio. Las
+// claves solo no son ambiguas si el código cumple ValidShortCode.
+func LinkKey(domain, shortCode string) string {
+	if domain == "" {
+		return shortCode
 	}
+	return domain + "/" + shortCode
 }
 
-func (s *Storage) Store(shortCode, longURL string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.urls[shortCode] = longURL
+// ValidShortCode indica si code puede formar parte de una clave: no puede
+// estar vacío ni contener "/", que separa el dominio del código en LinkKey.
+// Sin esta comprobación "acme.link/abc" en el dominio por defecto sería la
+// clave del código abc de acme.link.
+func ValidShortCode(code string) bool {
+	return code != "" && !strings.Contains(code, "/")
 }
 
-func (s *Storage) Get(shortCode string) (string, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	longURL, exists := s.urls[shortCode]
-	return longURL, exists
+// Expired indica si el enlace ha expirado en el instante now.
+func (l Link) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
 }
 
-func (s *Storage) Exists(shortCode string) bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	_, exists := s.urls[shortCode]
-	return exists
+// Storage define las operaciones que debe ofrecer cualquier backend de
+// almacenamiento. Shortener y los handlers dependen únicamente de esta
+// interfaz, por lo que los backends son intercambiables.
+//
+// Los enlaces se identifican por su clave (Link.Key), que combina el dominio y
+// el código.
+type Storage interface {
+	// Store guarda el enlace bajo link.Key(), reemplazando el anterior si existía.
+	Store(link Link) error
+	// StoreIfAbsent guarda el enlace solo si la clave no está en uso, de forma
+	// atómica. Devuelve false si la clave ya existía.
+	StoreIfAbsent(link Link) (bool, error)
+	// Get devuelve el enlace asociado a la clave y si existe.
+	Get(key string) (Link, bool)
+	// Exists indica si la clave ya está en uso.
+	Exists(key string) bool
+	// LookupURL busca en el índice inverso el enlace permanente (sin
+	// expiración) más reciente del dominio que apunta a longURL.
+	LookupURL(domain, longURL string) (Link, bool)
+	// Update aplica fn al enlace guardado y persiste el resultado de forma
+	// atómica. fn no puede cambiar el código ni el dominio. Devuelve
+	// ErrNotFound si la clave no existe, o el error de fn sin modificar nada.
+	Update(key string, fn func(Link) (Link, error)) (Link, error)
+	// Delete elimina el mapeo. Devuelve ErrNotFound si la clave no existe.
+	Delete(key string) error
+	// DeleteIf es como Delete, pero antes llama a fn con el enlace bajo el
+	// mismo bloqueo y, si fn devuelve un error, no borra nada y lo devuelve.
+	DeleteIf(key string, fn func(Link) error) error
+	// DeleteExpired elimina los enlaces expirados en now y devuelve las claves
+	// que borró, también las borradas antes de un error.
+	DeleteExpired(now time.Time) ([]string, error)
+	// List devuelve una copia de todos los mapeos ordenados por clave.
+	List() []Link
+	// ListPage devuelve hasta limit mapeos con clave mayor que after,
+	// ordenados por clave. after vacío empieza desde el principio.
+	ListPage(after string, limit int) []Link
+	// ListOwnedPage es como ListPage pero solo devuelve los mapeos de owner.
+	// Filtra en una sola pasada, sin recorrer el storage página a página.
+	ListOwnedPage(owner, after string, limit int) []Link
+	// Count devuelve el número de mapeos almacenados.
+	Count() int
+	// Close libera los recursos del backend y persiste lo pendiente.
+	Close() error
+}
+
+// RemovalListener recibe las claves de los enlaces que se eliminan sin pasar
+// por Shortener.Delete (expirados o expulsados), p. ej. para olvidar sus
+// estadísticas. Puede llamarse con locks del storage tomados, así que no debe
+// usar el storage.
+type RemovalListener func(keys []string)
+
+// Pinger lo implementan los backends que dependen de recursos externos (un
+// directorio, una conexión) y pueden comprobar si siguen disponibles.
+type Pinger interface {
+	// Ping devuelve un error si el backend no puede atender operaciones.
+	Ping(ctx context.Context) error
+}
+
+// Ping comprueba storage si implementa Pinger. Los backends en memoria están
+// siempre disponibles.
+func Ping(ctx context.Context, storage Storage) error {
+	if p, ok := storage.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// StorageOptions selecciona y configura el backend de almacenamiento.
+type StorageOptions struct {
+	// Backend es el nombre del backend ("memory", "sharded" o "file").
+	Backend string
+	// Shards es el número de shards del backend "sharded" (0 = cuatro por procesador).
+	Shards int
+	// Dir es el directorio de datos del backend "file".
+	Dir string
+	// File configura el backend "file".
+	File FileStorageOptions
+}
+
+// OpenStorage construye el backend indicado en opts.
+func OpenStorage(opts StorageOptions) (Storage, error) {
+	switch opts.Backend {
+	case "", "memory":
+		return NewMemoryStorage(), nil
+	case "sharded":
+		return NewShardedStorage(opts.Shards), nil
+	case "file":
+		if opts.Dir == "" {
+			return nil, fmt.Errorf("file storage requires a data directory")
+		}
+		return NewFileStorage(opts.Dir, opts.File)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", opts.Backend)
+	}
 }
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// Las pruebas de rendimiento comparan los backends en memoria con distintas
+// proporciones de lecturas y escrituras. Para variar GOMAXPROCS:
+//
+//	go test ./internal/service -run '^$' -bench BenchmarkStorage -cpu 1,4,16
+
+const benchPreloaded = 100_000
+
+var benchBackends = []struct {
+	name string
+	new  func() Storage
+}{
+	{"memory", func() Storage { return NewMemoryStorage() }},
+	{"sharded", func() Storage { return NewShardedStorage(0) }},
+}
+
+func preload(storage Storage) {
+	for i := 0; i < benchPreloaded; i++ {
+		storage.Store(Link{ShortCode: "c" + strconv.Itoa(i), LongURL: "https://example.com/" + strconv.Itoa(i)})
+	}
+}
+
+// BenchmarkStorage_Mixed mezcla Get (redirecciones) con StoreIfAbsent
+// (creaciones) en la proporción indicada desde varias goroutines.
+func BenchmarkStorage_Mixed(b *testing.B) {
+	for _, readPercent := range []int{100, 99, 90, 50} {
+		for _, backend := range benchBackends {
+			b.Run(fmt.Sprintf("reads=%d%%/%s", readPercent, backend.name), func(b *testing.B) {
+				storage := backend.new()
+				preload(storage)
+				var writes atomic.Int64
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := 0
+					for pb.Next() {
+						i++
+						if i%100 < readPercent {
+							storage.Get("c" + strconv.Itoa(i*7919%benchPreloaded))
+							continue
+						}
+						n := writes.Add(1)
+						storage.StoreIfAbsent(Link{ShortCode: "w" + strconv.FormatInt(n, 10), LongURL: "https://example.com/new"})
+					}
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkStorage_LookupURL mide la deduplicación mientras se crean enlaces.
+func BenchmarkStorage_LookupURL(b *testing.B) {
+	for _, backend := range benchBackends {
+		b.Run(backend.name, func(b *testing.B) {
+			storage := backend.new()
+			preload(storage)
+			var writes atomic.Int64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					i++
+					if i%10 == 0 {
+						n := writes.Add(1)
+						storage.StoreIfAbsent(Link{ShortCode: "w" + strconv.FormatInt(n, 10), LongURL: "https://example.com/w" + strconv.FormatInt(n, 10)})
+						continue
+					}
+					storage.LookupURL("", "https://example.com/"+strconv.Itoa(i%benchPreloaded))
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkStorage_ListOwnedPage mide una página de un propietario con pocos
+// enlaces entre muchos ajenos, el caso de una clave sin permiso admin.
+func BenchmarkStorage_ListOwnedPage(b *testing.B) {
+	for _, backend := range benchBackends {
+		b.Run(backend.name, func(b *testing.B) {
+			storage := backend.new()
+			preload(storage)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				storage.ListOwnedPage("alice", "", 51)
+			}
+		})
+	}
+}
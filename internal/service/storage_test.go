@@ -12,16 +12,16 @@ func TestStorage_Store(t *testing.T) {
 	shortCode := "abc123"
 	longURL := "https://www.google.com"
 	
-	storage.Store(shortCode, longURL)
+	storage.Store(Link{ShortCode: shortCode, LongURL: longURL})
 	
 	// Verificar que se almacenó correctamente
-	retrievedURL, exists := storage.Get(shortCode)
+	retrieved, exists := storage.Get(shortCode)
 	if !exists {
 		t.Error("Expected URL to exist in storage")
 	}
 	
-	if retrievedURL != longURL {
-		t.Errorf("Expected %s, got %s", longURL, retrievedURL)
+	if retrieved.LongURL != longURL {
+		t.Errorf("Expected %s, got %s", longURL, retrieved.LongURL)
 	}
 }
 
@@ -43,7 +43,7 @@ func TestStorage_Exists(t *testing.T) {
 	}
 	
 	// Almacenar y verificar que existe
-	storage.Store("test123", "https://test.com")
+	storage.Store(Link{ShortCode: "test123", LongURL: "https://test.com"})
 	if !storage.Exists("test123") {
 		t.Error("Expected true for existing key")
 	}
@@ -62,7 +62,7 @@ func TestStorage_ConcurrentAccess(t *testing.T) {
 			defer wg.Done()
 			shortCode := fmt.Sprintf("code%d", id)
 			longURL := fmt.Sprintf("https://test%d.com", id)
-			storage.Store(shortCode, longURL)
+			storage.Store(Link{ShortCode: shortCode, LongURL: longURL})
 		}(i)
 	}
 	
@@ -73,12 +73,12 @@ func TestStorage_ConcurrentAccess(t *testing.T) {
 		shortCode := fmt.Sprintf("code%d", i)
 		expectedURL := fmt.Sprintf("https://test%d.com", i)
 		
-		retrievedURL, exists := storage.Get(shortCode)
+		retrieved, exists := storage.Get(shortCode)
 		if !exists {
 			t.Errorf("Expected URL %s to exist", shortCode)
 		}
-		if retrievedURL != expectedURL {
-			t.Errorf("Expected %s, got %s", expectedURL, retrievedURL)
+		if retrieved.LongURL != expectedURL {
+			t.Errorf("Expected %s, got %s", expectedURL, retrieved.LongURL)
 		}
 	}
 }
@@ -90,7 +90,7 @@ func TestStorage_ConcurrentReadWrite(t *testing.T) {
 	for i := 0; i < 10; i++ {
 		shortCode := fmt.Sprintf("initial%d", i)
 		longURL := fmt.Sprintf("https://initial%d.com", i)
-		storage.Store(shortCode, longURL)
+		storage.Store(Link{ShortCode: shortCode, LongURL: longURL})
 	}
 	
 	var wg sync.WaitGroup
@@ -117,7 +117,7 @@ func TestStorage_ConcurrentReadWrite(t *testing.T) {
 			defer wg.Done()
 			shortCode := fmt.Sprintf("writer%d", id)
 			longURL := fmt.Sprintf("https://writer%d.com", id)
-			storage.Store(shortCode, longURL)
+			storage.Store(Link{ShortCode: shortCode, LongURL: longURL})
 		}(i)
 	}
 	
@@ -128,8 +128,8 @@ func TestStorage_ConcurrentReadWrite(t *testing.T) {
 		shortCode := fmt.Sprintf("initial%d", i)
 		expectedURL := fmt.Sprintf("https://initial%d.com", i)
 		
-		retrievedURL, exists := storage.Get(shortCode)
-		if !exists || retrievedURL != expectedURL {
+		retrieved, exists := storage.Get(shortCode)
+		if !exists || retrieved.LongURL != expectedURL {
 			t.Errorf("Data integrity compromised for %s", shortCode)
 		}
 	}
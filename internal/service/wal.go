@@ -0,0 +1,112 @@
+package service
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Formato de cada registro del log (write-ahead log):
+//
+//	[longitud uint32 LE][crc32c uint32 LE][payload JSON]
+//
+// El CRC cubre únicamente el payload.
+const (
+	walHeaderSize = 8
+	// walMaxRecordSize protege contra longitudes absurdas leídas de un registro corrupto.
+	walMaxRecordSize = 1 << 20
+)
+
+const (
+	walOpPut    = "put"
+	walOpDelete = "del"
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errWALCorrupt indica un registro corrupto que no está al final del log.
+var errWALCorrupt = errors.New("corrupt write-ahead log record")
+
+type walRecord struct {
+	Op string `json:"op"`
+	Link
+}
+
+// encodeWALRecord serializa un registro con su cabecera.
+func encodeWALRecord(rec walRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, walHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, walCRCTable))
+	copy(buf[walHeaderSize:], payload)
+	return buf, nil
+}
+
+// replayWAL lee todos los registros válidos de f y llama a apply para cada uno.
+// Si el último registro está incompleto o corrupto, el archivo se trunca al
+// final del último registro válido. Una corrupción seguida de más datos se
+// considera irrecuperable y devuelve errWALCorrupt.
+// Devuelve el offset donde deben continuar las escrituras.
+func replayWAL(f *os.File, apply func(walRecord)) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	header := make([]byte, walHeaderSize)
+	for offset < size {
+		if _, err := io.ReadFull(f, header); err != nil {
+			// Cabecera cortada: escritura interrumpida al final del log
+			return offset, truncateWAL(f, offset)
+		}
+		length := int64(binary.LittleEndian.Uint32(header[0:4]))
+		checksum := binary.LittleEndian.Uint32(header[4:8])
+		end := offset + walHeaderSize + length
+
+		if end > size {
+			// Registro cortado: escritura interrumpida al final del log
+			return offset, truncateWAL(f, offset)
+		}
+		if length > walMaxRecordSize {
+			return offset, fmt.Errorf("%w at offset %d", errWALCorrupt, offset)
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			return offset, truncateWAL(f, offset)
+		}
+
+		var rec walRecord
+		if crc32.Checksum(payload, walCRCTable) != checksum || json.Unmarshal(payload, &rec) != nil {
+			if end == size {
+				// Último registro corrupto: se descarta
+				return offset, truncateWAL(f, offset)
+			}
+			return offset, fmt.Errorf("%w at offset %d", errWALCorrupt, offset)
+		}
+
+		apply(rec)
+		offset = end
+	}
+	return offset, nil
+}
+
+func truncateWAL(f *os.File, offset int64) error {
+	if err := f.Truncate(offset); err != nil {
+		return fmt.Errorf("truncating write-ahead log: %w", err)
+	}
+	return f.Sync()
+}
@@ -0,0 +1,187 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// DefaultTrackingParams son los parámetros de seguimiento que se eliminan por
+// defecto. Un "*" final indica prefijo.
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// defaultPorts son los puertos que se omiten por ser los del esquema.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// NormalizeOptions configura NormalizeURL.
+type NormalizeOptions struct {
+	// TrackingParams son los parámetros de query que se eliminan (sin
+	// distinguir mayúsculas). Un "*" final indica prefijo, p. ej. "utm_*".
+	TrackingParams []string
+}
+
+// DefaultNormalizeOptions devuelve las opciones de normalización por defecto.
+func DefaultNormalizeOptions() NormalizeOptions {
+	return NormalizeOptions{TrackingParams: DefaultTrackingParams}
+}
+
+// NormalizeURL devuelve la forma canónica de rawURL para que URLs equivalentes
+// se guarden y deduplican igual:
+//   - esquema y host en minúsculas, y hosts IDN convertidos a Punycode
+//   - sin el puerto por defecto del esquema
+//   - segmentos "." y ".." resueltos (RFC 3986, sección 5.2.4)
+//   - una ruta "/" sola se omite
+//   - parámetros de seguimiento eliminados y el resto ordenados por nombre
+//
+// No valida la URL; solo falla si no puede interpretarla.
+func NormalizeURL(rawURL string, opts NormalizeOptions) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	if u.Host != "" {
+		host, err := normalizeHost(u.Scheme, u.Host)
+		if err != nil {
+			return "", err
+		}
+		u.Host = host
+	}
+
+	if u.Opaque == "" {
+		escaped := removeDotSegments(u.EscapedPath())
+		if escaped == "/" {
+			escaped = ""
+		}
+		path, err := url.PathUnescape(escaped)
+		if err != nil {
+			return "", err
+		}
+		u.Path, u.RawPath = path, escaped
+	}
+
+	u.RawQuery = normalizeQuery(u.RawQuery, opts.TrackingParams)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// normalizeHost pasa el host a minúsculas y ASCII y quita el puerto por defecto.
+func normalizeHost(scheme, hostport string) (string, error) {
+	host, port := hostport, ""
+	if i := strings.LastIndexByte(hostport, ':'); i >= 0 && !strings.Contains(hostport[i:], "]") {
+		host, port = hostport[:i], hostport[i+1:]
+	}
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+
+	// Los literales IPv6 van entre corchetes y no admiten Punycode
+	if !strings.HasPrefix(host, "[") {
+		ascii, err := HostToASCII(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid host %q: %w", host, err)
+		}
+		host = ascii
+	} else {
+		host = strings.ToLower(host)
+	}
+
+	if port == "" {
+		return host, nil
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port), nil
+}
+
+// removeDotSegments resuelve "." y ".." en una ruta según RFC 3986.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	var out []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			// Un "." final deja la ruta terminada en barra
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+
+	result := strings.Join(out, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(result, "/") {
+		result = "/" + result
+	}
+	return result
+}
+
+// normalizeQuery elimina los parámetros de seguimiento y ordena el resto por
+// nombre. Conserva la codificación original de cada par y, entre pares con el
+// mismo nombre, su orden relativo.
+func normalizeQuery(rawQuery string, tracking []string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type pair struct {
+		key string
+		raw string
+	}
+	var pairs []pair
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(raw, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if isTrackingParam(key, tracking) {
+			continue
+		}
+		pairs = append(pairs, pair{key: key, raw: raw})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].key < pairs[j].key
+	})
+
+	raws := make([]string, len(pairs))
+	for i, p := range pairs {
+		raws[i] = p.raw
+	}
+	return strings.Join(raws, "&")
+}
+
+func isTrackingParam(key string, tracking []string) bool {
+	for _, pattern := range tracking {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
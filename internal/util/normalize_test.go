@@ -0,0 +1,80 @@
+package util
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://www.google.com", "https://www.google.com"},
+		{"https://www.google.com/", "https://www.google.com"},
+		{"HTTPS://WWW.Google.COM/Path", "https://www.google.com/Path"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"http://example.com:443/a", "http://example.com:443/a"},
+		{"https://example.com/a/./b/../c", "https://example.com/a/c"},
+		{"https://example.com/a/b/..", "https://example.com/a/"},
+		{"https://example.com/../a", "https://example.com/a"},
+		{"https://example.com/a/", "https://example.com/a/"},
+		{"https://example.com/file.txt", "https://example.com/file.txt"},
+		{"https://example.com/?b=2&a=1&a=0", "https://example.com?a=1&a=0&b=2"},
+		{"https://example.com/p?utm_source=x&id=1&UTM_Medium=y&fbclid=z&gclid=w", "https://example.com/p?id=1"},
+		{"https://example.com/p?utm_source=x", "https://example.com/p"},
+		{"https://example.com/p?", "https://example.com/p"},
+		{"https://example.com/a%20b?q=a%2Bb", "https://example.com/a%20b?q=a%2Bb"},
+		{"https://example.com/p#Section", "https://example.com/p#Section"},
+		{"https://münchen.de/", "https://xn--mnchen-3ya.de"},
+		{"https://BÜCHER.example/x", "https://xn--bcher-kva.example/x"},
+		{"http://[::1]:80/", "http://[::1]"},
+		{"http://[::1]:8080/a", "http://[::1]:8080/a"},
+	}
+
+	for _, test := range tests {
+		result, err := NormalizeURL(test.input, DefaultNormalizeOptions())
+		if err != nil {
+			t.Errorf("NormalizeURL(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("NormalizeURL(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestNormalizeURL_CustomTrackingParams(t *testing.T) {
+	opts := NormalizeOptions{TrackingParams: []string{"ref", "mc_*"}}
+
+	result, err := NormalizeURL("https://example.com/?utm_source=x&ref=y&mc_cid=z&id=1", opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result != "https://example.com?id=1&utm_source=x" {
+		t.Errorf("Unexpected result %q", result)
+	}
+}
+
+func TestNormalizeURL_Idempotent(t *testing.T) {
+	inputs := []string{
+		"HTTPS://Example.com:443/a/../b/?z=1&utm_source=x&a=2",
+		"https://münchen.de/ü?x=1",
+	}
+
+	for _, input := range inputs {
+		once, err := NormalizeURL(input, DefaultNormalizeOptions())
+		if err != nil {
+			t.Fatalf("NormalizeURL(%q) returned error: %v", input, err)
+		}
+		twice, _ := NormalizeURL(once, DefaultNormalizeOptions())
+		if once != twice {
+			t.Errorf("Expected normalization to be idempotent: %q != %q", once, twice)
+		}
+	}
+}
+
+func TestNormalizeURL_InvalidInput(t *testing.T) {
+	if _, err := NormalizeURL("http://[::1", DefaultNormalizeOptions()); err == nil {
+		t.Error("Expected error for unparseable URL")
+	}
+}
@@ -0,0 +1,31 @@
+package util
+
+import "testing"
+
+func TestHostToASCII(t *testing.T) {
+	// Vectores de RFC 3492 y ejemplos habituales de IDN
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"example.com", "example.com"},
+		{"EXAMPLE.com", "example.com"},
+		{"münchen.de", "xn--mnchen-3ya.de"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"mañana.com", "xn--maana-pta.com"},
+		{"例え.jp", "xn--r8jz45g.jp"},
+		{"правительство.рф", "xn--80aealotwbjpid2k.xn--p1ai"},
+		{"ليهمابتكلموشعربي؟", "xn--egbpdaj6bu4bxfgehfvwxn"},
+	}
+
+	for _, test := range tests {
+		result, err := HostToASCII(test.input)
+		if err != nil {
+			t.Errorf("HostToASCII(%q) returned error: %v", test.input, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("HostToASCII(%q) = %q; expected %q", test.input, result, test.expected)
+		}
+	}
+}
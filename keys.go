@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/config"
+)
+
+const keysUsage = `uso: url-inteligente keys <comando> [flags]
+
+comandos:
+  mint -owner <nombre> -scopes create,read,manage,admin   crea una clave
+  list                                                    lista las claves
+  revoke <id>                                             revoca una clave
+
+Todos aceptan -keys-file; por defecto se usa el de la configuración
+(URLI_KEYS_FILE, o keys-file en el fichero de URLI_CONFIG).`
+
+// runKeys implementa el subcomando keys, que crea, lista y revoca claves de
+// API en el almacén sin arrancar el servidor.
+func runKeys(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+	command, args := args[0], args[1:]
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("keys "+command, flag.ContinueOnError)
+	fs.SetOutput(out)
+	keysFile := fs.String("keys-file", cfg.KeysFile, "almacén de claves de API")
+	owner := fs.String("owner", "", "propietario de los enlaces creados con la clave (vacío = el ID de la clave)")
+	scopes := fs.String("scopes", string(auth.ScopeCreate)+","+string(auth.ScopeRead), "permisos separados por comas")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keysFile == "" {
+		return errors.New("no key store configured: use -keys-file or URLI_KEYS_FILE")
+	}
+
+	store, err := auth.Open(*keysFile)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "mint":
+		parsed, err := auth.ParseScopes(*scopes)
+		if err != nil {
+			return err
+		}
+		key, token, err := store.Mint(*owner, parsed)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Clave %s creada para %q con permisos %s.\n", key.ID, key.Owner, joinScopes(key.Scopes))
+		fmt.Fprintf(out, "Guarda el token, no se volverá a mostrar:\n\n  %s\n", token)
+	case "list":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tPROPIETARIO\tPERMISOS\tCREADA\tESTADO")
+		for _, key := range store.List() {
+			status := "activa"
+			if key.Revoked() {
+				status = "revocada " + key.RevokedAt.Format("2006-01-02")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.ID, key.Owner, joinScopes(key.Scopes), key.CreatedAt.Format("2006-01-02"), status)
+		}
+		return w.Flush()
+	case "revoke":
+		if fs.NArg() != 1 {
+			return errors.New("usage: url-inteligente keys revoke <id>")
+		}
+		if err := store.Revoke(fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Clave %s revocada.\n", fs.Arg(0))
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, keysUsage)
+	}
+	return nil
+}
+
+func joinScopes(scopes []auth.Scope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jackparradev/url-inteligente/internal/auth"
+)
+
+func TestRunKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys.json")
+
+	var out strings.Builder
+	if err := runKeys([]string{"mint", "-keys-file", file, "-owner", "marketing", "-scopes", "create,manage"}, &out); err != nil {
+		t.Fatalf("Error minting key: %v", err)
+	}
+	token := regexp.MustCompile(`urli_\S+`).FindString(out.String())
+	if token == "" {
+		t.Fatalf("Expected a token in the output, got %q", out.String())
+	}
+
+	store, _ := auth.Open(file)
+	key, err := store.Authenticate(token)
+	if err != nil || key.Owner != "marketing" || !key.Has(auth.ScopeManage) || key.Has(auth.ScopeRead) {
+		t.Fatalf("Expected a marketing key with create,manage, got %+v (err=%v)", key, err)
+	}
+
+	out.Reset()
+	runKeys([]string{"list", "-keys-file", file}, &out)
+	if !strings.Contains(out.String(), key.ID) || !strings.Contains(out.String(), "activa") {
+		t.Errorf("Expected the key to be listed as active, got %q", out.String())
+	}
+
+	if err := runKeys([]string{"revoke", "-keys-file", file, key.ID}, &out); err != nil {
+		t.Fatalf("Error revoking key: %v", err)
+	}
+	store, _ = auth.Open(file)
+	if _, err := store.Authenticate(token); err == nil {
+		t.Error("Expected the key to be revoked")
+	}
+}
+
+func TestRunKeys_Errors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys.json")
+	tests := [][]string{
+		{},
+		{"rotate", "-keys-file", file},
+		{"mint"},
+		{"mint", "-keys-file", file, "-scopes", "delete"},
+		{"revoke", "-keys-file", file},
+		{"revoke", "-keys-file", file, "missing"},
+	}
+	for _, args := range tests {
+		t.Setenv("URLI_KEYS_FILE", "")
+		if err := runKeys(args, new(strings.Builder)); err == nil {
+			t.Errorf("Expected error for %v", args)
+		}
+	}
+}
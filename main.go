@@ -1,32 +1,302 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/jackparradev/url-inteligente/internal/analytics"
+	"github.com/jackparradev/url-inteligente/internal/auth"
+	"github.com/jackparradev/url-inteligente/internal/blocklist"
+	"github.com/jackparradev/url-inteligente/internal/config"
 	"github.com/jackparradev/url-inteligente/internal/handler"
+	"github.com/jackparradev/url-inteligente/internal/lifecycle"
+	"github.com/jackparradev/url-inteligente/internal/logging"
+	"github.com/jackparradev/url-inteligente/internal/metrics"
+	"github.com/jackparradev/url-inteligente/internal/ratelimit"
 	"github.com/jackparradev/url-inteligente/internal/service"
+	"github.com/jackparradev/url-inteligente/internal/util"
+	"github.com/jackparradev/url-inteligente/internal/validation"
 )
 
 func main() {
+	// El subcomando keys gestiona las claves de API sin arrancar el servidor
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error de configuración: %v", err)
+	}
+
+	// Logs estructurados; slog.SetDefault redirige también el paquete log
+	logger, err := logging.NewLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		log.Fatalf("Error de configuración: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	// SIGINT o SIGTERM inician el apagado ordenado. Tras la primera señal se
+	// restaura el comportamiento por defecto: una segunda termina sin esperar.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	listen := func() (net.Listener, error) { return net.Listen("tcp", cfg.ServerPort) }
+	if err := run(ctx, cfg, logger, listen); err != nil {
+		log.Fatalf("Error %v", err)
+	}
+	log.Printf("Servidor detenido")
+}
+
+// run monta el servicio, lo atiende en el listener que devuelve listen hasta
+// que ctx se cancela y entonces espera a las peticiones en curso y detiene
+// los componentes en orden inverso al de arranque, terminando por el storage.
+// listen se llama con todo ya montado: mientras se reproduce el log del
+// storage no se aceptan conexiones, así que las sondas fallan en el acto en
+// lugar de quedarse colgadas.
+func run(ctx context.Context, cfg *config.Config, logger *slog.Logger, listen func() (net.Listener, error)) error {
+	app := lifecycle.New(cfg.ShutdownTimeout, logger, lifecycle.WithDrainDelay(cfg.ShutdownDelay))
+	// Si el arranque falla, se detiene lo que ya se había iniciado
+	defer app.Stop()
+
 	// Inicializar el storage
-	storage := service.NewStorage()
+	storage, err := service.OpenStorage(service.StorageOptions{
+		Backend: cfg.StorageBackend,
+		Dir:     cfg.StorageDir,
+		Shards:  cfg.StorageShards,
+		File: service.FileStorageOptions{
+			Fsync:            service.FsyncPolicy(cfg.FsyncPolicy),
+			FsyncInterval:    cfg.FsyncInterval,
+			SnapshotInterval: cfg.SnapshotInterval,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("inicializando el storage: %w", err)
+	}
+	app.OnStop("storage", func(context.Context) error { return storage.Close() })
+
+	// Las estadísticas de un enlace purgado o expulsado se olvidan con él, para
+	// que un código reutilizado no herede sus clics
+	var aggregator *analytics.Aggregator
+	if cfg.Analytics {
+		aggregator = analytics.NewAggregator()
+	}
+	forgetStats := func(keys []string) {
+		if aggregator != nil {
+			aggregator.Forget(keys...)
+		}
+	}
+
+	// Con capacidad máxima, al llenarse se rechaza o se expulsa según la política
+	if cfg.MaxLinks > 0 || cfg.MaxStorageBytes > 0 {
+		bounded, err := service.NewBoundedStorage(storage, service.Capacity{
+			MaxEntries: cfg.MaxLinks,
+			MaxBytes:   cfg.MaxStorageBytes,
+			Policy:     service.EvictionPolicy(cfg.EvictionPolicy),
+			OnEvict:    forgetStats,
+		})
+		if err != nil {
+			return fmt.Errorf("aplicando la capacidad del storage: %w", err)
+		}
+		occupancy := bounded.Occupancy()
+		log.Printf("Capacidad del storage: %d/%d enlaces, %d/%d bytes (política %s)",
+			occupancy.Entries, occupancy.MaxEntries, occupancy.Bytes, occupancy.MaxBytes, cfg.EvictionPolicy)
+		storage = bounded
+	}
+
+	generator, err := service.NewCodeGenerator(service.GeneratorOptions{
+		Strategy: cfg.Generator,
+		Length:   cfg.ShortCodeLength,
+		Key:      cfg.GeneratorKey,
+	})
+	if err != nil {
+		return fmt.Errorf("inicializando el generador de códigos: %w", err)
+	}
+	// Los contadores siguen tras el mayor código guardado para no repetir
+	// códigos aunque se hayan borrado enlaces
+	service.ResumeGenerator(generator, storage.List())
 
 	// Inicializar el servicio shortener
-	shortener := service.NewShortener(storage)
+	opts := []service.ShortenerOption{
+		service.WithGenerator(generator),
+		service.WithMaxAttempts(cfg.MaxRetry),
+		service.WithDedup(cfg.Dedup),
+		service.WithLogger(logger),
+		service.WithNormalizeOptions(util.NormalizeOptions{TrackingParams: cfg.TrackingParams}),
+		service.WithValidationPolicy(validation.Policy{
+			AllowedSchemes:       cfg.AllowedSchemes,
+			MaxLength:            cfg.MaxURLLength,
+			AllowUserinfo:        cfg.AllowUserinfo,
+			AllowIPLiterals:      cfg.AllowIPLiterals,
+			AllowPrivateNetworks: cfg.AllowPrivateNetworks,
+			AllowedPorts:         cfg.AllowedPorts,
+		}),
+		service.WithAliasPolicy(service.AliasPolicy{
+			Charset:   cfg.AliasCharset,
+			MinLength: cfg.AliasMinLength,
+			MaxLength: cfg.AliasMaxLength,
+			Reserved:  cfg.ReservedWords,
+		}),
+	}
+
+	// La blocklist es opcional y se recarga al cambiar el fichero
+	if cfg.BlocklistFile != "" {
+		blocked, err := blocklist.New(cfg.BlocklistFile, cfg.BlocklistReloadInterval)
+		if err != nil {
+			return fmt.Errorf("cargando la blocklist: %w", err)
+		}
+		blocked.Start()
+		app.OnStopFunc("blocklist", blocked.Stop)
+		opts = append(opts, service.WithBlocklist(blocked))
+	}
+	shortener := service.NewShortener(storage, opts...)
+
+	// Purgar enlaces expirados en segundo plano
+	reaper := service.NewReaper(storage, cfg.ReaperInterval, service.SystemClock,
+		service.WithRemovalListener(forgetStats))
+	reaper.Start()
+	app.OnStopFunc("reaper", reaper.Stop)
+
+	handlerOpts := []handler.HandlerOption{
+		handler.WithBaseURL(cfg.BaseURL),
+		handler.WithDomains(cfg.Domains...),
+		handler.WithMaxBatchSize(cfg.BatchMaxSize),
+		handler.WithBatchTimeouts(cfg.ReadTimeout, cfg.WriteTimeout),
+		handler.WithDefaultRedirectType(cfg.DefaultRedirectType),
+		handler.WithRedirectCacheMaxAge(cfg.RedirectCacheMaxAge),
+		// /readyz falla si el storage no responde o si el servidor se está apagando;
+		// el log del backend file ya se reprodujo al abrirlo, antes de escuchar (ver run)
+		handler.WithReadinessCheck("storage", func(ctx context.Context) error { return service.Ping(ctx, storage) }),
+		handler.WithReadinessCheck("shutdown", func(context.Context) error { return app.Ready() }),
+	}
+
+	// Las métricas se registran aunque no se expongan: son solo contadores
+	reg := metrics.NewRegistry()
+	reg.RegisterRuntime()
+	httpMetrics := reg.NewHTTPMetrics()
+	registerServiceMetrics(reg, storage, shortener)
+	handlerOpts = append(handlerOpts, handler.WithMetrics(reg))
+
+	// Los clics se agregan en segundo plano para no frenar las redirecciones
+	if cfg.Analytics {
+		clicks := analytics.NewPipeline(analytics.Options{
+			BufferSize: cfg.AnalyticsBufferSize,
+			Salt:       []byte(cfg.AnalyticsSalt),
+		}, aggregator)
+		clicks.Start()
+		app.OnStopFunc("analytics", clicks.Stop)
+		reg.NewCounterFunc("urli_clicks_dropped_total", "Clicks dropped because the analytics queue was full.",
+			func() float64 { return float64(clicks.Dropped()) })
+		handlerOpts = append(handlerOpts, handler.WithAnalytics(clicks, aggregator))
+	}
+
+	// Con almacén de claves, la API exige una clave; las redirecciones no
+	if cfg.KeysFile != "" {
+		keys, err := auth.Open(cfg.KeysFile)
+		if err != nil {
+			return fmt.Errorf("cargando las claves de API: %w", err)
+		}
+		log.Printf("Autenticación activada (%d claves en %s)", len(keys.List()), cfg.KeysFile)
+		handlerOpts = append(handlerOpts, handler.WithAuth(keys))
+	}
+
+	// Límites de ritmo por cliente; las cubetas inactivas se descartan para
+	// que el limitador no crezca sin fin
+	createLimit := ratelimit.New(ratelimit.Limit{Rate: cfg.CreateRateLimit, Burst: cfg.CreateRateBurst})
+	batchLimit := ratelimit.New(ratelimit.Limit{Rate: cfg.BatchRateLimit, Burst: cfg.BatchRateBurst})
+	redirectLimit := ratelimit.New(ratelimit.Limit{Rate: cfg.RedirectRateLimit, Burst: cfg.RedirectRateBurst})
+	createLimit.Start(cfg.RateLimitSweepInterval)
+	app.OnStopFunc("create rate limit", createLimit.Stop)
+	batchLimit.Start(cfg.RateLimitSweepInterval)
+	app.OnStopFunc("batch rate limit", batchLimit.Stop)
+	redirectLimit.Start(cfg.RateLimitSweepInterval)
+	app.OnStopFunc("redirect rate limit", redirectLimit.Stop)
+	handlerOpts = append(handlerOpts,
+		handler.WithRateLimits(createLimit, redirectLimit),
+		handler.WithBatchRateLimit(batchLimit))
 
 	// Inicializar handlers
-	h := handler.NewHandler(shortener)
+	h := handler.NewHandler(shortener, handlerOpts...)
 
 	// Configurar rutas
 	mux := http.NewServeMux()
-	mux.HandleFunc("/shorten", h.ShortenURL)
-	mux.HandleFunc("/", h.RedirectURL)
+	mux.HandleFunc("/shorten", h.Require(auth.ScopeCreate, h.LimitCreate(h.ShortenURL)))
+	mux.HandleFunc("POST /api/v1/shorten/batch", h.Require(auth.ScopeCreate, h.LimitCreate(h.ShortenBatch)))
+	mux.HandleFunc("GET /api/links/{code}/stats", h.Require(auth.ScopeRead, h.LinkStats))
+	mux.HandleFunc("GET /api/v1/links", h.Require(auth.ScopeRead, h.ListLinks))
+	mux.HandleFunc("GET /api/v1/links/{code}", h.Require(auth.ScopeRead, h.GetLink))
+	mux.HandleFunc("PATCH /api/v1/links/{code}", h.Require(auth.ScopeManage, h.UpdateLink))
+	mux.HandleFunc("DELETE /api/v1/links/{code}", h.Require(auth.ScopeManage, h.DeleteLink))
+	mux.HandleFunc("GET /healthz", h.Healthz)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+	mux.HandleFunc("GET /version", h.Version)
+	mux.HandleFunc("/", h.LimitRedirect(h.RedirectURL))
+	if cfg.Metrics {
+		mux.HandleFunc("GET /metrics", reg.Handler())
+	}
+
+	// Iniciar servidor; los timeouts evitan que un cliente lento retenga conexiones
+	server := &http.Server{
+		Handler:           logging.AccessLog(logger, httpMetrics.Instrument(mux)),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	ln, err := listen()
+	if err != nil {
+		return fmt.Errorf("escuchando en %s: %w", cfg.ServerPort, err)
+	}
+	defer ln.Close()
+	log.Printf("Servidor iniciado en %s (base: %s, storage: %s)", ln.Addr(), cfg.BaseURL, cfg.StorageBackend)
+	return app.Serve(ctx, server, ln)
+}
 
-	// Puerto hardcodeado según restricciones
-	port := ":8080"
+// registerServiceMetrics expone los contadores del shortener y la ocupación
+// del storage, que se leen en cada consulta de /metrics.
+func registerServiceMetrics(reg *metrics.Registry, storage service.Storage, shortener *service.Shortener) {
+	reg.NewCounterFunc("urli_shorten_attempts_total", "Generated short codes tried while creating links.",
+		func() float64 { return float64(shortener.Stats().Attempts) })
+	reg.NewCounterFunc("urli_shorten_collisions_total", "Generated short codes discarded because they were taken or reserved.",
+		func() float64 { return float64(shortener.Stats().Collisions) })
+	reg.NewCounterFunc("urli_shorten_exhausted_total", "Link creations that ran out of attempts.",
+		func() float64 { return float64(shortener.Stats().Exhausted) })
+	reg.NewGaugeFunc("urli_storage_links", "Links currently stored.",
+		func() float64 { return float64(storage.Count()) })
 
-	// Iniciar servidor
-	log.Printf("Servidor iniciado en %s", port)
-	log.Fatal(http.ListenAndServe(port, mux))
+	bounded, ok := storage.(*service.BoundedStorage)
+	if !ok {
+		return
+	}
+	reg.NewGaugeFunc("urli_storage_bytes", "Approximate memory used by stored links.",
+		func() float64 { return float64(bounded.Occupancy().Bytes) })
+	reg.NewGaugeFunc("urli_storage_max_links", "Maximum number of links (0 = unlimited).",
+		func() float64 { return float64(bounded.Capacity().MaxEntries) })
+	reg.NewGaugeFunc("urli_storage_max_bytes", "Maximum approximate memory for links (0 = unlimited).",
+		func() float64 { return float64(bounded.Capacity().MaxBytes) })
+	reg.NewCounterFunc("urli_storage_evictions_total", "Links evicted to make room for new ones.",
+		func() float64 { return float64(bounded.Occupancy().Evictions) })
+	reg.NewCounterFunc("urli_storage_rejections_total", "Links rejected because storage was full.",
+		func() float64 { return float64(bounded.Occupancy().Rejections) })
 }
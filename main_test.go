@@ -36,8 +36,8 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 	
 	mux.ServeHTTP(rr, req)
 	
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rr.Code)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", rr.Code)
 	}
 	
 	var response handler.ShortenResponse
@@ -55,8 +55,8 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 	
 	mux.ServeHTTP(redirectRR, redirectReq)
 	
-	if redirectRR.Code != http.StatusMovedPermanently {
-		t.Errorf("Expected status 301, got %d", redirectRR.Code)
+	if redirectRR.Code != http.StatusFound {
+		t.Errorf("Expected status 302, got %d", redirectRR.Code)
 	}
 	
 	location := redirectRR.Header().Get("Location")
@@ -95,7 +95,7 @@ func TestIntegration_ConcurrentRequests(t *testing.T) {
 			
 			mux.ServeHTTP(rr, req)
 			
-			if rr.Code != http.StatusOK {
+			if rr.Code != http.StatusCreated {
 				t.Errorf("Request %d failed with status %d", id, rr.Code)
 			}
 		}(i)
@@ -155,8 +155,8 @@ func TestIntegration_MultipleURLsSameEndpoint(t *testing.T) {
 		
 		mux.ServeHTTP(rr, req)
 		
-		if rr.Code != http.StatusMovedPermanently {
-			t.Errorf("Expected status 301 for %s, got %d", shortCode, rr.Code)
+		if rr.Code != http.StatusFound {
+			t.Errorf("Expected status 302 for %s, got %d", shortCode, rr.Code)
 		}
 		
 		location := rr.Header().Get("Location")
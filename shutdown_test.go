@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackparradev/url-inteligente/internal/config"
+	"github.com/jackparradev/url-inteligente/internal/handler"
+	"github.com/jackparradev/url-inteligente/internal/service"
+)
+
+// listener adapta ln al argumento listen de run.
+func listener(ln net.Listener) func() (net.Listener, error) {
+	return func() (net.Listener, error) { return ln, nil }
+}
+
+// TestRun_ListensAfterStorage comprueba que no se escucha hasta tener el
+// storage abierto: si no se puede abrir, no se llega a escuchar.
+func TestRun_ListensAfterStorage(t *testing.T) {
+	cfg := config.Default()
+	// Un fichero donde debería haber un directorio hace fallar la apertura
+	notDir := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(notDir, nil, 0o600)
+	cfg.StorageBackend, cfg.StorageDir = "file", notDir
+
+	listened := false
+	listen := func() (net.Listener, error) {
+		listened = true
+		return net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err := run(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), listen); err == nil {
+		t.Fatal("Expected run to fail with an unusable storage dir")
+	}
+	if listened {
+		t.Error("Expected run not to listen before the storage is open")
+	}
+}
+
+// TestRun_GracefulShutdown comprueba que una petición aceptada antes de la
+// señal de apagado termina con éxito y que sus enlaces llegan al disco.
+func TestRun_GracefulShutdown(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.StorageBackend, cfg.StorageDir, cfg.FsyncPolicy = "file", dir, "never"
+	cfg.CreateRateLimit = 0
+	cfg.ShutdownTimeout = 5 * time.Second
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	base := "http://" + ln.Addr().String()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, logger, listener(ln)) }()
+
+	// Un lote NDJSON cuyo cuerpo se envía en dos partes: la petición está en
+	// curso cuando llega la señal y termina de enviarse durante el apagado
+	body, send := io.Pipe()
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(base+"/api/v1/shorten/batch", "application/x-ndjson", body)
+		responses <- result{resp, err}
+	}()
+	const items = 5
+	fmt.Fprintf(send, "{\"url\":\"https://example.com/0\"}\n")
+	waitFor(t, func() bool { return countLinks(base) == 1 })
+
+	cancel()
+	// El apagado empieza cerrando el listener
+	waitFor(t, func() bool {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		return err != nil
+	})
+	for i := 1; i < items; i++ {
+		fmt.Fprintf(send, "{\"url\":\"https://example.com/%d\"}\n", i)
+	}
+	send.Close()
+
+	res := <-responses
+	if res.err != nil {
+		t.Fatalf("Expected in-flight request to complete, got %v", res.err)
+	}
+	defer res.resp.Body.Close()
+	if res.resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", res.resp.StatusCode)
+	}
+	created := 0
+	scanner := bufio.NewScanner(res.resp.Body)
+	for scanner.Scan() {
+		var item handler.BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			t.Fatalf("Error decoding batch result: %v", err)
+		}
+		if item.Status != http.StatusCreated {
+			t.Errorf("Expected item %d to be created, got %d", item.Index, item.Status)
+		}
+		created++
+	}
+	if created != items {
+		t.Errorf("Expected %d results, got %d", items, created)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected run to return after shutdown")
+	}
+
+	// El storage se cerró al final: todo lo aceptado está en disco
+	storage, err := service.NewFileStorage(dir, service.FileStorageOptions{})
+	if err != nil {
+		t.Fatalf("Error reopening storage: %v", err)
+	}
+	defer storage.Close()
+	if got := storage.Count(); got != items {
+		t.Errorf("Expected %d persisted links, got %d", items, got)
+	}
+}
+
+// countLinks devuelve los enlaces que lista la API, o -1 si falla.
+func countLinks(base string) int {
+	resp, err := http.Get(base + "/api/v1/links")
+	if err != nil {
+		return -1
+	}
+	defer resp.Body.Close()
+	var list handler.LinkListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return -1
+	}
+	return len(list.Links)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRun_Probes comprueba que /readyz deja de anunciar el servidor en cuanto
+// llega la señal, mientras -shutdown-delay lo mantiene atendiendo.
+func TestRun_Probes(t *testing.T) {
+	cfg := config.Default()
+	cfg.ShutdownDelay = 300 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %v", err)
+	}
+	base := "http://" + ln.Addr().String()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, logger, listener(ln)) }()
+
+	for _, path := range []string{"/healthz", "/readyz", "/version"} {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatalf("Error requesting %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected %s to return 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	cancel()
+	waitFor(t, func() bool {
+		resp, err := http.Get(base + "/readyz")
+		if err != nil {
+			t.Fatalf("Expected requests during the shutdown delay to be served, got %v", err)
+		}
+		defer resp.Body.Close()
+		var response handler.HealthResponse
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode == http.StatusServiceUnavailable && response.Checks["shutdown"] != "ok"
+	})
+
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+}